@@ -0,0 +1,171 @@
+package okrs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileCache stores API responses as JSON files in a directory. It is shared by all sources.
+type fileCache struct {
+	dir    string
+	prefix string // prefix of file names, specific to the source
+	ttl    time.Duration
+	// refresh ignores the TTL, so all entries must be revalidated.
+	refresh bool
+	// offline only uses cached entries, regardless of their age.
+	offline bool
+}
+
+// cacheEntry is a cached API response with information required to revalidate it.
+type cacheEntry struct {
+	Time         time.Time       `json:"time"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// setHeaders makes the request conditional, so the server can reply with 304 Not Modified.
+func (e *cacheEntry) setHeaders(req *http.Request) {
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+// parseCacheTTL parses the maximal age of cache entries. Zero means that entries never expire.
+func parseCacheTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache ttl: %v", err)
+	}
+	return d, nil
+}
+
+func (c *fileCache) path(key string) string {
+	return filepath.Join(c.dir, c.prefix+key+".json")
+}
+
+// checkOffline returns an error in offline mode. It must be called before requests that are not cached.
+func (c *fileCache) checkOffline(key string) error {
+	if c.offline {
+		return fmt.Errorf("%s is not cached, cannot fetch it in offline mode", key)
+	}
+	return nil
+}
+
+// read returns a cache entry regardless of its age.
+func (c *fileCache) read(key string) *cacheEntry {
+	if c.dir == "" {
+		return nil
+	}
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println(err)
+		}
+		return nil
+	}
+	defer f.Close()
+	var e cacheEntry
+	if err = json.NewDecoder(f).Decode(&e); err != nil || len(e.Data) == 0 {
+		// written by an older version, or truncated
+		return nil
+	}
+	return &e
+}
+
+// get decodes a fresh cache entry. Entries older than the TTL are ignored, unless in offline mode.
+func (c *fileCache) get(key string, out interface{}) bool {
+	e := c.read(key)
+	if e == nil {
+		return false
+	}
+	if !c.offline && (c.refresh || (c.ttl > 0 && time.Since(e.Time) > c.ttl)) {
+		return false
+	}
+	if err := json.Unmarshal(e.Data, out); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// stale returns an expired cache entry, if any, so it can be revalidated with a conditional request.
+func (c *fileCache) stale(key string) *cacheEntry {
+	e := c.read(key)
+	if e == nil || (e.ETag == "" && e.LastModified == "") {
+		return nil
+	}
+	return e
+}
+
+// notModified checks if the server confirmed that the cache entry is still valid.
+// If so, the entry is decoded and its timestamp is updated.
+func (c *fileCache) notModified(key string, e *cacheEntry, resp *http.Response, out interface{}) bool {
+	if e == nil || resp == nil || resp.StatusCode != http.StatusNotModified {
+		return false
+	}
+	if err := json.Unmarshal(e.Data, out); err != nil {
+		log.Println(err)
+		return false
+	}
+	e.Time = time.Now()
+	if v := resp.Header.Get("ETag"); v != "" {
+		e.ETag = v
+	}
+	if v := resp.Header.Get("Last-Modified"); v != "" {
+		e.LastModified = v
+	}
+	c.writeEntry(key, e)
+	return true
+}
+
+// put stores the data with validators from the response, if it's set.
+func (c *fileCache) put(key string, data interface{}, resp *http.Response) {
+	if c.dir == "" {
+		return
+	}
+	buf, err := json.Marshal(data)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	e := &cacheEntry{Time: time.Now(), Data: buf}
+	if resp != nil {
+		e.ETag = resp.Header.Get("ETag")
+		e.LastModified = resp.Header.Get("Last-Modified")
+	}
+	c.writeEntry(key, e)
+}
+
+func (c *fileCache) writeEntry(key string, e *cacheEntry) {
+	_ = os.MkdirAll(c.dir, 0755)
+	err := writeFileAtomic(c.path(key), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(e)
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (c *fileCache) drop(key string) error {
+	if c.dir == "" {
+		return nil
+	}
+	err := os.Remove(c.path(key))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return err
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/dennwc/okrs"
@@ -24,10 +27,14 @@ var (
 				return err
 			}
 			if g := c.Github; g != nil {
+				setGithubFlags(cmd, g)
 				if g.Cache != "" {
 					log.Println("using cache from", g.Cache)
 				}
 			}
+			if j := c.Jira; j != nil {
+				setJiraFlags(cmd, j)
+			}
 			return c.Run(context.TODO())
 		},
 	}
@@ -39,18 +46,231 @@ func main() {
 	}
 }
 
+// setGithubFlags applies Github-related flags to the Github source. Flags override values from the config.
+func setGithubFlags(cmd *cobra.Command, g *okrs.Github) {
+	if tok, _ := cmd.Flags().GetString("auth"); tok != "" {
+		g.Token = tok
+	}
+	if u, _ := cmd.Flags().GetString("base-url"); u != "" {
+		g.BaseURL = u
+	}
+	g.Refresh, _ = cmd.Flags().GetBool("refresh")
+	g.Offline, _ = cmd.Flags().GetBool("offline")
+}
+
+// setJiraFlags applies cache flags to the Jira source.
+func setJiraFlags(cmd *cobra.Command, j *okrs.Jira) {
+	j.Refresh, _ = cmd.Flags().GetBool("refresh")
+	j.Offline, _ = cmd.Flags().GetBool("offline")
+}
+
 func registerTreeWriterFlags(flags *pflag.FlagSet) {
 	flags.StringP("out", "o", "json", "output format to use")
+	flags.String("rollup", "", "progress rollup mode (count or weighted)")
+	flags.String("weights", "", "weights for weighted rollup (equal or priority)")
+	flags.String("owner", "", "only output objectives owned by a given user or team")
+	flags.String("period", "", "only output objectives from a given OKR period (for example, 2026-Q4)")
 }
 
 func writeTree(name string, cmd *cobra.Command, tree *okrs.Tree) error {
+	var r okrs.Rollup
+	mode, _ := cmd.Flags().GetString("rollup")
+	r.Mode = okrs.RollupMode(mode)
+	weights, _ := cmd.Flags().GetString("weights")
+	r.Weights = okrs.WeightsMode(weights)
+	if err := r.Apply(tree.Root()); err != nil {
+		return err
+	}
 	format, _ := cmd.Flags().GetString("out")
-	o := okrs.Output{Format: format, Path: name}
+	owner, _ := cmd.Flags().GetString("owner")
+	period, _ := cmd.Flags().GetString("period")
+	o := okrs.Output{Format: format, Path: name, Owner: owner, Period: period}
 	return o.WriteTree(tree)
 }
 
 func init() {
 	Root.Flags().StringP("conf", "c", "okrs.yml", "config file path")
+	Root.PersistentFlags().Bool("refresh", false, "refetch all cached Github and Jira responses, ignoring cache TTL")
+	Root.PersistentFlags().Bool("offline", false, "only use cached Github and Jira responses")
+
+	ConvertCmd := &cobra.Command{
+		Use:   "convert [FILE]",
+		Short: "read OKR tree in one of the supported formats and write it in another",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("expected one argument")
+			}
+			in := okrs.Input{}
+			in.Format, _ = cmd.Flags().GetString("in")
+			if len(args) == 1 {
+				in.Path = args[0]
+			}
+			if in.Format == "" && (in.Path == "" || in.Path == "-") {
+				in.Format = "md"
+			}
+			tr := okrs.NewTree()
+			if err := in.ReadTree(tr); err != nil {
+				return err
+			}
+			dst, _ := cmd.Flags().GetString("dst")
+			return writeTree(dst, cmd, tr)
+		},
+	}
+	ConvertCmd.Flags().StringP("in", "i", "", "input format to use (detected from the file extension by default)")
+	ConvertCmd.Flags().String("dst", "-", "output file path")
+	registerTreeWriterFlags(ConvertCmd.Flags())
+	Root.AddCommand(ConvertCmd)
+
+	ValidateCmd := &cobra.Command{
+		Use:   "validate [FILE...]",
+		Short: "check OKR tree for structural problems",
+		Long:  "Check OKR tree loaded from files or from inputs specified in the config for structural problems.\nExits with non-zero code if any errors are found.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				// structural errors found while loading are reported as problems
+				tr   = okrs.NewLenientTree()
+				lint okrs.Lint
+			)
+			if len(args) != 0 {
+				for _, arg := range args {
+					if err := (okrs.Input{Path: arg}).ReadTree(tr); err != nil {
+						return err
+					}
+				}
+			} else {
+				conf, _ := cmd.Flags().GetString("conf")
+				c, err := okrs.ReadConfig(conf)
+				if err != nil {
+					return err
+				}
+				if c.Lint != nil {
+					lint = *c.Lint
+				}
+				if c.Github != nil {
+					setGithubFlags(cmd, c.Github)
+				}
+				if c.Jira != nil {
+					setJiraFlags(cmd, c.Jira)
+				}
+				if err = c.ReadTree(context.TODO(), tr); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("max-depth") {
+				lint.MaxDepth, _ = cmd.Flags().GetInt("max-depth")
+			}
+			if cmd.Flags().Changed("require-owners") {
+				lint.RequireOwners, _ = cmd.Flags().GetBool("require-owners")
+			}
+			if disable, _ := cmd.Flags().GetStringSlice("disable"); len(disable) != 0 {
+				lint.Disable = append(lint.Disable, disable...)
+			}
+			problems := lint.Check(tr)
+			format, _ := cmd.Flags().GetString("format")
+			switch format {
+			case "json":
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "\t")
+				if problems == nil {
+					problems = []okrs.Problem{}
+				}
+				if err := enc.Encode(problems); err != nil {
+					return err
+				}
+			case "", "text":
+				for _, p := range problems {
+					fmt.Println(p)
+				}
+			default:
+				return fmt.Errorf("unknown format: %q", format)
+			}
+			if errs := okrs.CountSeverity(problems, okrs.SeverityError); errs != 0 {
+				cmd.SilenceUsage = true
+				warns := okrs.CountSeverity(problems, okrs.SeverityWarning)
+				return fmt.Errorf("found %d errors and %d warnings", errs, warns)
+			}
+			return nil
+		},
+	}
+	ValidateCmd.Flags().StringP("conf", "c", "okrs.yml", "config file path")
+	ValidateCmd.Flags().String("format", "text", "output format (text or json)")
+	ValidateCmd.Flags().Int("max-depth", 0, "maximal depth of key results")
+	ValidateCmd.Flags().Bool("require-owners", false, "report objectives without owners as errors")
+	ValidateCmd.Flags().StringSlice("disable", nil, "lint rules to disable")
+	Root.AddCommand(ValidateCmd)
+
+	DiffCmd := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "show how OKR tree changed between two snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("expected two arguments")
+			}
+			var trees [2]*okrs.Tree
+			for i, arg := range args {
+				trees[i] = okrs.NewTree()
+				if err := (okrs.Input{Path: arg}).ReadTree(trees[i]); err != nil {
+					return err
+				}
+			}
+			changes := trees[0].Diff(trees[1])
+			format, _ := cmd.Flags().GetString("format")
+			switch format {
+			case "", "md":
+				return okrs.WriteDiffMD(os.Stdout, changes)
+			case "json":
+				return okrs.WriteDiffJSON(os.Stdout, changes)
+			}
+			return fmt.Errorf("unknown format: %q", format)
+		},
+	}
+	DiffCmd.Flags().String("format", "md", "output format (md or json)")
+	Root.AddCommand(DiffCmd)
+
+	HistoryCmd := &cobra.Command{
+		Use:   "history",
+		Short: "show progress of objectives over time",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			h := &okrs.History{}
+			h.Path, _ = cmd.Flags().GetString("store")
+			if h.Path == "" {
+				conf, _ := cmd.Flags().GetString("conf")
+				c, err := okrs.ReadConfig(conf)
+				if err != nil {
+					return err
+				}
+				if c.History == nil {
+					return errors.New("history store is not configured")
+				}
+				h = c.History
+			}
+			snaps, err := h.Load()
+			if err != nil {
+				return err
+			}
+			series := okrs.NewSeries(snaps)
+			format, _ := cmd.Flags().GetString("format")
+			switch format {
+			case "json":
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "\t")
+				return enc.Encode(series)
+			case "", "text":
+			default:
+				return fmt.Errorf("unknown format: %q", format)
+			}
+			for _, s := range series {
+				first, last := s.Points[0], s.Points[len(s.Points)-1]
+				fmt.Printf("%s\t%3.0f%% -> %3.0f%%\t%s\n", okrs.Sparkline(s.Fractions()),
+					first.Progress.Fraction()*100, last.Progress.Fraction()*100, s.Key)
+			}
+			return nil
+		},
+	}
+	HistoryCmd.Flags().StringP("conf", "c", "okrs.yml", "config file path")
+	HistoryCmd.Flags().String("store", "", "path to the history store (overrides the config)")
+	HistoryCmd.Flags().String("format", "text", "output format (text or json)")
+	Root.AddCommand(HistoryCmd)
 
 	MDCmd := &cobra.Command{
 		Use:   "md",
@@ -91,40 +311,89 @@ func init() {
 		Use:   "github",
 		Short: "Github-related tools",
 	}
-	GHCmd.PersistentFlags().String("auth", "", "github auth token")
+	GHCmd.PersistentFlags().String("auth", "", "github auth token (by default, taken from the config or the gh CLI)")
 	GHCmd.PersistentFlags().String("org", "", "github org")
+	GHCmd.PersistentFlags().String("base-url", "", "API URL of Github Enterprise Server (for example, https://github.example.com/api/v3/)")
 	Root.AddCommand(GHCmd)
-	/*
-		GHProjTree := &cobra.Command{
-			Use:   "proj [PROJECTNAME]",
-			Short: "load OKR tree from Github project",
-			RunE: func(cmd *cobra.Command, args []string) error {
-				gh := &okrs.Github{}
-				gh.Token, _ = cmd.Flags().GetString("auth")
-				org, _ := cmd.Flags().GetString("org")
-				if org == "" {
-					return errors.New("organization should be specified")
-				}
-				o := okrs.GHOrg{Name: org}
-				for _, arg := range args {
-					o.Projects = append(o.Projects, okrs.GHProject{Name: arg})
+	GHProjTree := &cobra.Command{
+		Use:   "proj [PROJECT...]",
+		Short: "load OKR tree from Github project",
+		Long:  "Load OKR tree from Github project boards of the organization. Projects can be selected by name or by ID.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("expected at least one project")
+			}
+			gh := &okrs.Github{}
+			setGithubFlags(cmd, gh)
+			org, _ := cmd.Flags().GetString("org")
+			if org == "" {
+				return errors.New("organization should be specified")
+			}
+			done, _ := cmd.Flags().GetStringSlice("done")
+			o := okrs.GHOrg{Name: org}
+			for _, arg := range args {
+				p := okrs.GHProject{Name: arg, DoneColumns: done}
+				if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+					p = okrs.GHProject{ID: id, DoneColumns: done}
 				}
-				gh.Orgs = append(gh.Orgs, o)
-				tr := okrs.NewTree()
-				err := gh.LoadTree(context.TODO(), tr)
+				o.Projects = append(o.Projects, p)
+			}
+			gh.Orgs = append(gh.Orgs, o)
+			tr := okrs.NewTree()
+			err := gh.LoadTree(context.TODO(), tr)
+			if err != nil {
+				return err
+			}
+			name := org
+			if len(args) == 1 {
+				name += "_" + strings.Replace(args[0], " ", "_", -1)
+			}
+			return writeTree(name, cmd, tr)
+		},
+	}
+	GHProjTree.Flags().StringSlice("done", nil, "project columns that mark cards as completed (default \"Done\")")
+	registerTreeWriterFlags(GHProjTree.Flags())
+	GHCmd.AddCommand(GHProjTree)
+
+	GHProjV2Tree := &cobra.Command{
+		Use:   "projv2 NUMBER...",
+		Short: "load OKR tree from Github projects (v2)",
+		Long:  "Load OKR tree from Github projects (v2) of the organization. Custom fields like Priority, Quarter and Target are mapped to OKR fields.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("expected at least one project number")
+			}
+			gh := &okrs.Github{}
+			setGithubFlags(cmd, gh)
+			org, _ := cmd.Flags().GetString("org")
+			if org == "" {
+				return errors.New("organization should be specified")
+			}
+			done, _ := cmd.Flags().GetStringSlice("done")
+			o := okrs.GHOrg{Name: org}
+			for _, arg := range args {
+				num, err := strconv.Atoi(arg)
 				if err != nil {
-					return err
-				}
-				name := org
-				if len(args) == 1 {
-					name += "_" + strings.Replace(args[0], " ", "_", -1)
+					return fmt.Errorf("invalid project number %q: %v", arg, err)
 				}
-				return writeTree(name, cmd, tr)
-			},
-		}
-		registerTreeWriterFlags(GHProjTree.Flags())
-		GHCmd.AddCommand(GHProjTree)
-	*/
+				o.ProjectsV2 = append(o.ProjectsV2, okrs.GHProjectV2{Number: num, DoneStatus: done})
+			}
+			gh.Orgs = append(gh.Orgs, o)
+			tr := okrs.NewTree()
+			err := gh.LoadTree(context.TODO(), tr)
+			if err != nil {
+				return err
+			}
+			name := org
+			if len(args) == 1 {
+				name += "_" + args[0]
+			}
+			return writeTree(name, cmd, tr)
+		},
+	}
+	GHProjV2Tree.Flags().StringSlice("done", nil, "status values that mark items as completed (default \"Done\")")
+	registerTreeWriterFlags(GHProjV2Tree.Flags())
+	GHCmd.AddCommand(GHProjV2Tree)
 
 	GHRepoTree := &cobra.Command{
 		Use:   "repo [NAME]",
@@ -134,7 +403,7 @@ func init() {
 				return errors.New("expected one argument")
 			}
 			gh := &okrs.Github{}
-			gh.Token, _ = cmd.Flags().GetString("auth")
+			setGithubFlags(cmd, gh)
 			org, _ := cmd.Flags().GetString("org")
 			rname := args[0]
 			if org == "" && rname != "" {
@@ -166,4 +435,82 @@ func init() {
 	}
 	registerTreeWriterFlags(GHRepoTree.Flags())
 	GHCmd.AddCommand(GHRepoTree)
+
+	GHPublish := &cobra.Command{
+		Use:   "publish FILE",
+		Short: "create Github issues for objectives and key results from a markdown file",
+		Long:  "Create an issue for each objective and key result from a markdown file and link them with parent references.\nIssues that already exist with the same title are not created again.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("expected one argument")
+			}
+			gh := &okrs.Github{}
+			if conf, _ := cmd.Flags().GetString("conf"); conf != "" {
+				c, err := okrs.ReadConfig(conf)
+				if err != nil {
+					return err
+				}
+				if c.Github != nil {
+					gh = c.Github
+				}
+			}
+			setGithubFlags(cmd, gh)
+			org, _ := cmd.Flags().GetString("org")
+			rname, _ := cmd.Flags().GetString("repo")
+			if org == "" {
+				if i := strings.Index(rname, "/"); i > 0 {
+					org, rname = rname[:i], rname[i+1:]
+				}
+			}
+			if org == "" {
+				return errors.New("organization should be specified")
+			} else if rname == "" {
+				return errors.New("repository should be specified")
+			}
+			rname = strings.TrimPrefix(rname, org+"/")
+			tr := okrs.NewTree()
+			in := okrs.Input{Path: args[0], Format: "md"}
+			if err := in.ReadTree(tr); err != nil {
+				return err
+			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return gh.Publish(context.TODO(), os.Stdout, org, rname, tr, dryRun)
+		},
+	}
+	GHPublish.Flags().StringP("conf", "c", "", "config file path (for github token and cache)")
+	GHPublish.Flags().String("repo", "", "repository to create issues in (org/name)")
+	GHPublish.Flags().Bool("dry-run", false, "print planned issues instead of creating them")
+	GHCmd.AddCommand(GHPublish)
+
+	GHSync := &cobra.Command{
+		Use:   "sync",
+		Short: "write computed progress back to Github issues from the config",
+		Long:  "Write computed progress and a checklist of sub-issues to a generated block in the body of each Github issue.\nText outside of the block is preserved.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, _ := cmd.Flags().GetString("conf")
+			c, err := okrs.ReadConfig(conf)
+			if err != nil {
+				return err
+			}
+			if c.Github == nil {
+				return errors.New("github is not configured")
+			}
+			setGithubFlags(cmd, c.Github)
+			ctx := context.TODO()
+			tr, err := c.LoadTree(ctx)
+			if err != nil {
+				return err
+			}
+			if c.Rollup != nil {
+				if err = c.Rollup.Apply(tr.Root()); err != nil {
+					return err
+				}
+			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return c.Github.SyncProgress(ctx, os.Stdout, dryRun)
+		},
+	}
+	GHSync.Flags().StringP("conf", "c", "okrs.yml", "config file path")
+	GHSync.Flags().Bool("dry-run", false, "print planned changes as a diff instead of editing issues")
+	GHCmd.AddCommand(GHSync)
 }
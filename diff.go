@@ -0,0 +1,320 @@
+package okrs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded    = ChangeType("added")
+	ChangeRemoved  = ChangeType("removed")
+	ChangeMoved    = ChangeType("moved")
+	ChangePriority = ChangeType("priority")
+	ChangeProgress = ChangeType("progress")
+)
+
+// Change describes a single difference between two OKR trees.
+type Change struct {
+	Type  ChangeType `json:"type"`
+	Title string     `json:"title"`
+	Link  Link       `json:"url,omitempty"`
+
+	OldParent string `json:"old_parent,omitempty"`
+	NewParent string `json:"new_parent,omitempty"`
+
+	OldPriority *int `json:"old_priority,omitempty"`
+	NewPriority *int `json:"new_priority,omitempty"`
+
+	OldProgress *Progress `json:"old_progress,omitempty"`
+	NewProgress *Progress `json:"new_progress,omitempty"`
+}
+
+type diffEntry struct {
+	node   *Node
+	parent *Node
+}
+
+// flatten returns all nodes of the tree with their parents, in depth-first order.
+// Proxy nodes are skipped and their children are attached to the parent of the proxy.
+func flatten(root *Node) []diffEntry {
+	if root == nil {
+		return nil
+	}
+	var out []diffEntry
+	seen := make(map[*Node]struct{})
+	var walk func(parent, n *Node)
+	walk = func(parent, n *Node) {
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		if n.isProxyNode() {
+			for _, s := range n.Sub {
+				walk(parent, s)
+			}
+			return
+		}
+		out = append(out, diffEntry{node: n, parent: parent})
+		for _, s := range n.Sub {
+			walk(n, s)
+		}
+	}
+	walk(nil, root)
+	return out
+}
+
+// Diff compares the tree with a newer version of it. Nodes are matched by ID, then by URL and then by title.
+func (tr *Tree) Diff(newer *Tree) []Change {
+	olds := flatten(acyclic(tr.Root()))
+	news := flatten(acyclic(newer.Root()))
+
+	// match nodes by ID first, then by URL and title
+	keys := []func(n *Node) string{
+		func(n *Node) string { return n.ID },
+		func(n *Node) string { return n.Link.URL },
+		func(n *Node) string { return n.Title },
+	}
+	matched := make(map[*Node]struct{})
+	toOld := make(map[*Node]*Node)
+	for _, key := range keys {
+		index := make(map[string]*Node)
+		for _, e := range olds {
+			if _, ok := matched[e.node]; ok {
+				continue
+			}
+			if k := key(e.node); k != "" {
+				if _, ok := index[k]; !ok {
+					index[k] = e.node
+				}
+			}
+		}
+		for _, e := range news {
+			if _, ok := toOld[e.node]; ok {
+				continue
+			}
+			k := key(e.node)
+			if k == "" {
+				continue
+			}
+			if o, ok := index[k]; ok {
+				delete(index, k)
+				matched[o] = struct{}{}
+				toOld[e.node] = o
+			}
+		}
+	}
+	parentName := func(n *Node) string {
+		if n == nil {
+			return ""
+		}
+		return nodeName(n)
+	}
+	var out []Change
+	oldParents := make(map[*Node]*Node, len(olds))
+	for _, e := range olds {
+		oldParents[e.node] = e.parent
+	}
+	for _, e := range news {
+		n := e.node
+		c := Change{Title: n.Title, Link: n.Link}
+		if c.Title == "" {
+			c.Title = nodeName(n)
+		}
+		o, ok := toOld[n]
+		if !ok {
+			c.Type = ChangeAdded
+			c.NewParent = parentName(e.parent)
+			c.NewPriority = n.Priority
+			p := n.GetProgress()
+			c.NewProgress = &p
+			out = append(out, c)
+			continue
+		}
+		op := oldParents[o]
+		var np *Node
+		if e.parent != nil {
+			np = toOld[e.parent]
+			if np == nil {
+				np = e.parent
+			}
+		}
+		if op != np {
+			c := c
+			c.Type = ChangeMoved
+			c.OldParent = parentName(op)
+			c.NewParent = parentName(e.parent)
+			out = append(out, c)
+		}
+		if !equalPriority(o.Priority, n.Priority) {
+			c := c
+			c.Type = ChangePriority
+			c.OldPriority, c.NewPriority = o.Priority, n.Priority
+			out = append(out, c)
+		}
+		if !hasMeasurableProgress(o) || !hasMeasurableProgress(n) {
+			// progress of nodes without any progress information is meaningless
+			continue
+		}
+		if p1, p2 := o.GetProgress(), n.GetProgress(); p1.Fraction() != p2.Fraction() {
+			c := c
+			c.Type = ChangeProgress
+			c.OldProgress, c.NewProgress = &p1, &p2
+			out = append(out, c)
+		}
+	}
+	for _, e := range olds {
+		if _, ok := matched[e.node]; ok {
+			continue
+		}
+		n := e.node
+		c := Change{Type: ChangeRemoved, Title: n.Title, Link: n.Link}
+		if c.Title == "" {
+			c.Title = nodeName(n)
+		}
+		c.OldParent = parentName(e.parent)
+		c.OldPriority = n.Priority
+		p := n.GetProgress()
+		c.OldProgress = &p
+		out = append(out, c)
+	}
+	return out
+}
+
+// hasMeasurableProgress checks if the node or any of its sub-nodes has progress information,
+// either explicit, derived from a metric, or rolled up. Empty leaves are not measurable.
+func hasMeasurableProgress(n *Node) bool {
+	seen := make(map[*Node]struct{})
+	var check func(n *Node) bool
+	check = func(n *Node) bool {
+		if _, ok := seen[n]; ok {
+			return false
+		}
+		seen[n] = struct{}{}
+		if n.Status == StatusCanceled {
+			return false
+		} else if n.Progress != nil && *n.Progress != (Progress{}) || n.rollup != nil {
+			return true
+		} else if n.Metric != nil {
+			if _, ok := n.Metric.Progress(); ok {
+				return true
+			}
+		}
+		for _, s := range n.Sub {
+			if check(s) {
+				return true
+			}
+		}
+		return false
+	}
+	return check(n)
+}
+
+func equalPriority(p1, p2 *int) bool {
+	if p1 == nil || p2 == nil {
+		return p1 == p2
+	}
+	return *p1 == *p2
+}
+
+func formatPriority(p *int) string {
+	if p == nil {
+		return "none"
+	}
+	return fmt.Sprintf("P%d", *p)
+}
+
+func formatPerc(p Progress) string {
+	return fmt.Sprintf("%d%%", int(math.Round(p.Fraction()*100)))
+}
+
+// WriteDiffJSON writes a list of changes as JSON.
+func WriteDiffJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(changes)
+}
+
+// WriteDiffMD writes a list of changes as a Markdown changelog.
+func WriteDiffMD(w io.Writer, changes []Change) error {
+	var last error
+	write := func(format string, args ...interface{}) {
+		_, err := fmt.Fprintf(w, format, args...)
+		if err != nil {
+			last = err
+		}
+	}
+	sections := []struct {
+		typ   ChangeType
+		title string
+	}{
+		{ChangeAdded, "Added"},
+		{ChangeRemoved, "Removed"},
+		{ChangeMoved, "Moved"},
+		{ChangePriority, "Priority changes"},
+		{ChangeProgress, "Progress"},
+	}
+	if len(changes) == 0 {
+		write("No changes.\n")
+		return last
+	}
+	for _, sec := range sections {
+		first := true
+		for _, c := range changes {
+			if c.Type != sec.typ {
+				continue
+			}
+			if first {
+				write("## %s\n\n", sec.title)
+				first = false
+			}
+			title := c.Title
+			if u := c.Link; u.URL != "" {
+				txt := "link"
+				if u.Title != "" {
+					txt = u.Title
+				}
+				title += fmt.Sprintf(" ([%s](%s))", txt, u.URL)
+			}
+			switch c.Type {
+			case ChangeAdded:
+				if c.NewPriority != nil {
+					title = fmt.Sprintf("[P%d] %s", *c.NewPriority, title)
+				}
+				if c.NewParent != "" {
+					title += " under " + c.NewParent
+				}
+			case ChangeRemoved:
+				if c.OldParent != "" {
+					title += " from " + c.OldParent
+				}
+			case ChangeMoved:
+				from, to := c.OldParent, c.NewParent
+				if from == "" {
+					from = "top level"
+				}
+				if to == "" {
+					to = "top level"
+				}
+				title += fmt.Sprintf(": %s → %s", from, to)
+			case ChangePriority:
+				title += fmt.Sprintf(": %s → %s", formatPriority(c.OldPriority), formatPriority(c.NewPriority))
+			case ChangeProgress:
+				p1, p2 := *c.OldProgress, *c.NewProgress
+				delta := int(math.Round((p2.Fraction() - p1.Fraction()) * 100))
+				title += fmt.Sprintf(": %s → %s (%+d%%)", formatPerc(p1), formatPerc(p2), delta)
+			}
+			write("* %s\n", title)
+		}
+		if !first {
+			write("\n")
+		}
+	}
+	return last
+}
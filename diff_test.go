@@ -0,0 +1,63 @@
+package okrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Tree{root: &Node{
+		Title: "OKRs",
+		Sub: []*Node{
+			{Title: "Objective A", Sub: []*Node{
+				{Title: "KR 1", Link: Link{"#1", "https://github.com/org/repo/issues/1"}, Priority: pri(2)},
+				{Title: "KR 2", Progress: &Progress{Done: 20, Total: 100}},
+			}},
+			{Title: "Objective B", Sub: []*Node{
+				{Title: "KR 3"},
+			}},
+		},
+	}}
+	cur := &Tree{root: &Node{
+		Title: "OKRs",
+		Sub: []*Node{
+			{Title: "Objective A", Sub: []*Node{
+				{Title: "KR 1 renamed", Link: Link{"#1", "https://github.com/org/repo/issues/1"}, Priority: pri(1)},
+			}},
+			{Title: "Objective B", Sub: []*Node{
+				{Title: "KR 2", Progress: &Progress{Done: 45, Total: 100}},
+			}},
+			{Title: "Objective C"},
+		},
+	}}
+	// progress changes of Objective A and B are not reported: A has no measurable progress after KR 2 moved,
+	// and B had none before
+	changes := old.Diff(cur)
+	buf := bytes.NewBuffer(nil)
+	err := WriteDiffMD(buf, changes)
+	require.NoError(t, err)
+	require.Equal(t, `## Added
+
+* Objective C under OKRs
+
+## Removed
+
+* KR 3 from Objective B
+
+## Moved
+
+* KR 2: Objective A → Objective B
+
+## Priority changes
+
+* KR 1 renamed ([#1](https://github.com/org/repo/issues/1)): P2 → P1
+
+## Progress
+
+* OKRs: 50% → 67% (+17%)
+* KR 2: 20% → 45% (+25%)
+
+`, buf.String())
+}
@@ -0,0 +1,137 @@
+package okrs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterTreeWriter(TreeWriterDesc{
+		Name: "dot", Ext: "dot",
+		Write: WriteDotTree,
+	})
+}
+
+// dotQuote escapes the string to be used as a quoted ID in Graphviz DOT file.
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// dotColor returns the fill color for a node with a given progress.
+func dotColor(p Progress) string {
+	switch {
+	case p == (Progress{}):
+		return "#e0e0e0"
+	case p.IsDone():
+		return "#a5d6a7"
+	case p.Done == 0:
+		return "#ef9a9a"
+	default:
+		return "#fff59d"
+	}
+}
+
+func WriteDotTree(w io.Writer, tree *Node) error {
+	var last error
+	write := func(format string, args ...interface{}) {
+		_, err := fmt.Fprintf(w, format, args...)
+		if err != nil {
+			last = err
+		}
+	}
+	ids := make(map[*Node]string)
+	byLink := make(map[string]*Node)
+	var nodes []*Node
+	var index func(n *Node)
+	index = func(n *Node) {
+		if _, ok := ids[n]; ok {
+			return
+		}
+		ids[n] = fmt.Sprintf("n%d", len(ids)+1)
+		nodes = append(nodes, n)
+		if u := n.Link.URL; u != "" {
+			byLink[u] = n
+		}
+		for _, s := range n.Sub {
+			index(s)
+		}
+	}
+	index(tree)
+
+	write("digraph okrs {\n")
+	write("\trankdir=LR;\n")
+	write("\tnode [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, n := range nodes {
+		title := n.Title
+		if title == "" {
+			title = n.Link.Title
+		}
+		if n.Priority != nil {
+			title = fmt.Sprintf("[P%d] %s", *n.Priority, title)
+		}
+		label := title
+		p := n.GetProgress()
+		if p != (Progress{}) {
+			if p.Total == 100 {
+				label += fmt.Sprintf("\n%d%%", p.Done)
+			} else {
+				label += fmt.Sprintf("\n%d/%d", p.Done, p.Total)
+			}
+		}
+		if m := n.Metric; m != nil {
+			label += "\n" + m.String()
+		}
+		if len(n.Owners) != 0 {
+			label += "\n" + formatOwners(n.Owners)
+		}
+		if n.Due != nil {
+			label += "\ndue " + formatDate(*n.Due)
+		}
+		color := dotColor(p)
+		if n.Status == StatusCanceled {
+			label += "\n" + string(n.Status)
+			color = "#e0e0e0"
+		}
+		attrs := []string{
+			"label=" + dotQuote(label),
+			"fillcolor=" + dotQuote(color),
+		}
+		if n.Priority != nil {
+			attrs = append(attrs, fmt.Sprintf("priority=%d", *n.Priority))
+		}
+		if u := n.Link.URL; u != "" {
+			attrs = append(attrs, "URL="+dotQuote(u))
+		}
+		if n.Desc != "" {
+			attrs = append(attrs, "tooltip="+dotQuote(n.Desc))
+		}
+		write("\t%s [%s];\n", ids[n], strings.Join(attrs, ", "))
+	}
+	ext := 0
+	for _, n := range nodes {
+		for _, s := range n.Sub {
+			write("\t%s -> %s;\n", ids[n], ids[s])
+		}
+		for _, l := range n.Links {
+			if l2, ok := byLink[l.URL]; ok {
+				write("\t%s -> %s [style=dashed];\n", ids[n], ids[l2])
+				continue
+			}
+			// link points outside of the tree - draw it as a separate node
+			ext++
+			id := fmt.Sprintf("l%d", ext)
+			title := l.Title
+			if title == "" {
+				title = l.URL
+			}
+			write("\t%s [shape=plaintext, style=\"\", label=%s, URL=%s];\n", id, dotQuote(title), dotQuote(l.URL))
+			write("\t%s -> %s [style=dashed];\n", ids[n], id)
+		}
+	}
+	write("}\n")
+	return last
+}
@@ -0,0 +1,62 @@
+package okrs
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// checkGolden compares the output with the golden file from testdata.
+func checkGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, ioutil.WriteFile(path, got, 0644))
+	}
+	exp, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(exp), string(got))
+}
+
+// writerTestTree returns a tree that covers most node fields supported by writers.
+func writerTestTree() *Node {
+	shipped := &Node{
+		Title: "Ship v2", Priority: pri(0),
+		Link:     Link{Title: "#1", URL: "https://github.com/org/repo/issues/1"},
+		Progress: done(),
+		Owners:   []string{"alice"},
+		Desc:     "Release <b>v2</b> & **celebrate**",
+	}
+	hire := &Node{
+		Title:    "Hire engineers",
+		Progress: &Progress{Done: 1, Total: 4},
+		Due:      date("2026-12-31"),
+		Links:    []Link{{Title: "#1", URL: "https://github.com/org/repo/issues/1"}},
+	}
+	dropped := &Node{Title: "Dropped \"idea\"", Status: StatusCanceled}
+	todo := &Node{
+		Title:    "Migrate",
+		Progress: &Progress{Done: 0, Total: 1},
+		Links:    []Link{{Title: "Design doc", URL: "https://example.com/doc"}},
+	}
+	return &Node{
+		Title: "Company",
+		Sub: []*Node{
+			{Title: "Grow", Priority: pri(1), Sub: []*Node{shipped, hire, dropped}},
+			{Title: "Empty"},
+			todo,
+		},
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteDotTree(&buf, writerTestTree())
+	require.NoError(t, err)
+	checkGolden(t, "tree.dot", buf.Bytes())
+}
@@ -43,6 +43,40 @@ func RegisterTreeWriter(d TreeWriterDesc) {
 	treeWriters[d.Name] = d
 }
 
+type TreeReaderDesc struct {
+	Name string
+	Ext  string
+	Read func(r io.Reader, tr *Tree) error
+}
+
+var treeReaders = make(map[string]TreeReaderDesc)
+
+func TreeReaders() []TreeReaderDesc {
+	var out []TreeReaderDesc
+	for _, d := range treeReaders {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func TreeReader(name string) *TreeReaderDesc {
+	d, ok := treeReaders[name]
+	if !ok {
+		return nil
+	}
+	return &d
+}
+
+func RegisterTreeReader(d TreeReaderDesc) {
+	if _, ok := treeReaders[d.Name]; ok {
+		panic(d.Name + " is already registered")
+	}
+	treeReaders[d.Name] = d
+}
+
 func DumpTree(name string, tr *Tree) error {
 	var wr *TreeWriterDesc
 	ext := filepath.Ext(name)
@@ -67,5 +101,5 @@ func DumpTree(name string, tr *Tree) error {
 		defer f.Close()
 		w = f
 	}
-	return wr.Write(w, tr.root)
+	return wr.Write(w, tr.Root())
 }
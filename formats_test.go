@@ -0,0 +1,67 @@
+package okrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var casesRoundTrip = []struct {
+	format string
+	exp    *Node
+}{
+	{
+		format: "json",
+		exp: &Node{
+			Title: "Root",
+			Desc:  "Some description",
+			Sub: []*Node{
+				{Title: "sub 1", Priority: pri(0), Link: Link{"#21", "https://github.com/org/repo/issues/21"}},
+				{Title: "sub 2", Progress: done(), Sub: []*Node{
+					{Title: "sub 2.1", Progress: &Progress{Done: 2, Total: 3}},
+				}},
+			},
+		},
+	},
+	{
+		format: "yaml",
+		exp: &Node{
+			Title: "Root",
+			Sub: []*Node{
+				{Title: "sub 1", Priority: pri(1), Kind: KindKeyResult, Links: []Link{{URL: "https://example.com"}}},
+				{Title: "sub 2", Progress: done(), Status: StatusDone},
+			},
+		},
+	},
+	{
+		format: "mindmup",
+		exp: &Node{
+			Title: "Root",
+			Sub: []*Node{
+				{Title: "sub 1", Link: Link{URL: "https://example.com"}},
+				{Title: "sub 2", Kind: KindObjective, Sub: []*Node{
+					{Title: "sub 2.1"},
+					{Title: "sub 2.2", Status: StatusCanceled},
+				}},
+			},
+		},
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range casesRoundTrip {
+		t.Run(c.format, func(t *testing.T) {
+			w, r := TreeWriter(c.format), TreeReader(c.format)
+			require.NotNil(t, w)
+			require.NotNil(t, r)
+			buf := bytes.NewBuffer(nil)
+			err := w.Write(buf, c.exp)
+			require.NoError(t, err)
+			tr := NewTree()
+			err = r.Read(buf, tr)
+			require.NoError(t, err)
+			require.Equal(t, c.exp, tr.Root())
+		})
+	}
+}
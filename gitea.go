@@ -0,0 +1,323 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Gitea loads OKRs from issues of a Gitea or Forgejo instance.
+//
+// Issues use the same conventions as on Github: parents are referenced with "**Parent:** #N" in the body,
+// or with "org/repo#N" and issue URLs for parents in other repositories. Labels are mapped the same way as well.
+// Items listed in the body become children of the issue. Referenced issues from repositories that are not
+// configured are fetched on demand.
+type Gitea struct {
+	// URL is the address of the instance, for example https://codeberg.org.
+	URL   string `json:"url" yaml:"url"`
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// TokenEnv is the name of the environment variable with the API token.
+	TokenEnv string   `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	Orgs     []GTOrg  `json:"orgs,omitempty" yaml:"orgs,omitempty"`
+	Labels   GHLabels `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	hcli *http.Client
+}
+
+// GTOrg selects repositories of an organization or a user.
+type GTOrg struct {
+	Name string `json:"name" yaml:"name"`
+	// Repos is a list of repositories to load. If it is empty, all repositories of the organization are loaded,
+	// which is not supported for users.
+	Repos []GTRepo `json:"repos,omitempty" yaml:"repos,omitempty"`
+}
+
+type GTRepo struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+type gtIssue struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	State     string `json:"state"`
+	URL       string `json:"url"`
+	HTMLURL   string `json:"html_url"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Milestone *struct {
+		Title string     `json:"title"`
+		DueOn *time.Time `json:"due_on"`
+	} `json:"milestone"`
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+func (g *Gitea) baseURL() string {
+	return strings.TrimSuffix(g.URL, "/")
+}
+
+// client returns a client of Gitea API that authorizes requests with a given token.
+func (g *Gitea) client(tok string) *restClient {
+	return &restClient{
+		name: "gitea", base: g.baseURL() + "/api/v1", hcli: g.hcli,
+		auth: func(req *http.Request) {
+			if tok != "" {
+				req.Header.Set("Authorization", "token "+tok)
+			}
+		},
+		page: func(page int) string {
+			return fmt.Sprintf("limit=50&page=%d", page)
+		},
+		hasNext: func(resp *http.Response) bool {
+			return strings.Contains(resp.Header.Get("Link"), `rel="next"`)
+		},
+	}
+}
+
+func (is *gtIssue) labels() []github.Label {
+	out := make([]github.Label, 0, len(is.Labels))
+	for _, l := range is.Labels {
+		name := l.Name
+		out = append(out, github.Label{Name: &name})
+	}
+	return out
+}
+
+// isPull checks if the issue is a pull request.
+func (is *gtIssue) isPull() bool {
+	return len(is.PullRequest) != 0 && string(is.PullRequest) != "null"
+}
+
+// asNode creates a node for an issue, including fields from its body.
+func (g *Gitea) asNode(tr *Tree, is *gtIssue) (*Node, *Node, error) {
+	title, pri := issueTitle(is.Title)
+	nd := tr.NewNode(Node{
+		ID: is.URL, Title: title,
+		Link:     Link{Title: fmt.Sprintf("#%d", is.Number), URL: is.HTMLURL},
+		Priority: pri,
+	})
+	for _, u := range is.Assignees {
+		nd.Owners = appendOwners(nd.Owners, u.Login)
+	}
+	if m := is.Milestone; m != nil {
+		nd.Period = parsePeriod(m.Title)
+		nd.Due = m.DueOn
+	}
+	if is.State == "closed" {
+		nd.Status = StatusDone
+	}
+	g.Labels.apply(nd, is.labels())
+	if nd.Status == StatusDone {
+		markDone(nd)
+	}
+	local := NewTree()
+	// skip the progress block written by the sync
+	body, _ := splitSyncBlock(is.Body)
+	if err := ParseMDTree(strings.NewReader(body), local); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse %s: %v", is.HTMLURL, err)
+	}
+	lroot := local.Root()
+	if lroot != nil {
+		mergeLocal(nd, lroot)
+	}
+	return nd, lroot, nil
+}
+
+// gtRepo is a repository with its issues.
+type gtRepo struct {
+	org, name string
+	node      *Node
+	issues    []*gtIssue
+	loaded    bool // all issues of the repository are listed
+}
+
+// gtLoader loads issues of a Gitea instance and resolves references between them.
+type gtLoader struct {
+	g     *Gitea
+	cli   *restClient
+	tr    *Tree
+	res   *issueResolver
+	repos []*gtRepo
+	// byRepo indexes repositories by "org/repo"
+	byRepo  map[string]*gtRepo
+	byKey   map[string]*trackerIssue // by "org/repo#N"
+	skipped map[string]bool          // issues excluded by filters
+}
+
+// repo returns a repository, creating its node if necessary.
+func (l *gtLoader) repo(org, name string) *gtRepo {
+	key := org + "/" + name
+	if r := l.byRepo[key]; r != nil {
+		return r
+	}
+	r := &gtRepo{
+		org: org, name: name,
+		node: l.tr.NewNode(Node{
+			Title: name,
+			Link:  Link{Title: name, URL: fmt.Sprintf("%s/%s/%s", l.g.baseURL(), org, name)},
+		}),
+	}
+	l.byRepo[key] = r
+	l.repos = append(l.repos, r)
+	return r
+}
+
+// loadOrg lists issues of all selected repositories of the organization.
+func (l *gtLoader) loadOrg(ctx context.Context, org GTOrg) error {
+	names := make([]string, 0, len(org.Repos))
+	for _, r := range org.Repos {
+		names = append(names, r.Name)
+	}
+	if len(names) == 0 {
+		var repos []struct {
+			Name     string `json:"name"`
+			Archived bool   `json:"archived"`
+		}
+		if err := l.cli.list(ctx, "orgs/"+url.PathEscape(org.Name)+"/repos", &repos); err != nil {
+			return err
+		}
+		for _, r := range repos {
+			if !r.Archived {
+				names = append(names, r.Name)
+			}
+		}
+	}
+	for _, name := range names {
+		r := l.repo(org.Name, name)
+		if r.loaded {
+			continue // repository is listed twice
+		}
+		path := fmt.Sprintf("repos/%s/%s/issues?state=all&type=issues", url.PathEscape(org.Name), url.PathEscape(name))
+		if err := l.cli.list(ctx, path, &r.issues); err != nil {
+			return err
+		}
+		r.loaded = true
+	}
+	return nil
+}
+
+// add creates a node for the issue and registers it in the resolver.
+// It returns nil if the issue is excluded by filters.
+func (l *gtLoader) add(r *gtRepo, is *gtIssue) (*trackerIssue, error) {
+	key := issueKey(r.org, r.name, is.Number)
+	if is.isPull() || !l.g.Labels.match(is.labels()) {
+		l.skipped[key] = true
+		return nil, nil
+	}
+	nd, local, err := l.g.asNode(l.tr, is)
+	if err != nil {
+		return nil, err
+	}
+	ti := &trackerIssue{node: nd, local: local, scope: r.org + "/" + r.name, top: r.node}
+	l.byKey[key] = ti
+	l.res.add(ti)
+	return ti, nil
+}
+
+// parseRef resolves a link to an issue, relative to a given repository.
+func (l *gtLoader) parseRef(scope string, ln Link) (org, repo string, num int, ok bool) {
+	if sub := reLocalRef.FindStringSubmatch(ln.Title); len(sub) != 0 {
+		parts := strings.SplitN(scope, "/", 2)
+		num, _ = strconv.Atoi(sub[1])
+		return parts[0], parts[1], num, true
+	} else if sub = reRepoRef.FindStringSubmatch(ln.Title); len(sub) != 0 {
+		num, _ = strconv.Atoi(sub[3])
+		return sub[1], sub[2], num, true
+	}
+	return l.g.parseIssueURL(ln.URL)
+}
+
+// lookup finds an issue referenced from the body of another issue.
+// Issues from repositories that are not loaded are fetched on demand.
+func (l *gtLoader) lookup(ctx context.Context, from *trackerIssue, ln Link) (*trackerIssue, bool, error) {
+	org, repo, num, ok := l.parseRef(from.scope, ln)
+	if !ok {
+		return nil, false, nil
+	}
+	key := issueKey(org, repo, num)
+	if is := l.byKey[key]; is != nil {
+		return is, false, nil
+	} else if l.skipped[key] {
+		return nil, true, nil
+	} else if r := l.byRepo[org+"/"+repo]; r != nil && r.loaded {
+		return nil, false, nil
+	}
+	log.Printf("fetching %s", key)
+	var is gtIssue
+	err := l.cli.get(ctx, fmt.Sprintf("repos/%s/%s/issues/%d", url.PathEscape(org), url.PathEscape(repo), num), &is)
+	if isNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("cannot fetch %s: %v", key, err)
+	}
+	ti, err := l.add(l.repo(org, repo), &is)
+	return ti, ti == nil && err == nil, err
+}
+
+// LoadTree reads issues of all configured repositories.
+func (g *Gitea) LoadTree(ctx context.Context, tr *Tree) error {
+	if g.URL == "" {
+		return fmt.Errorf("gitea: url is not set")
+	} else if err := checkURL("gitea url", g.URL); err != nil {
+		return err
+	}
+	tok, err := configToken(g.Token, g.TokenEnv)
+	if err != nil {
+		return err
+	}
+	l := &gtLoader{
+		g: g, cli: g.client(tok), tr: tr,
+		byRepo:  make(map[string]*gtRepo),
+		byKey:   make(map[string]*trackerIssue),
+		skipped: make(map[string]bool),
+	}
+	l.res = &issueResolver{tr: tr, tracker: l}
+	for _, org := range g.Orgs {
+		if err := l.loadOrg(ctx, org); err != nil {
+			return err
+		}
+	}
+	for _, r := range l.repos {
+		for _, is := range r.issues {
+			if _, err := l.add(r, is); err != nil {
+				return err
+			}
+		}
+	}
+	if err := l.res.resolve(ctx); err != nil {
+		return err
+	}
+	nodes := make([]*Node, 0, len(l.repos))
+	for _, r := range l.repos {
+		nodes = append(nodes, r.node)
+	}
+	return addContainers(tr, nodes)
+}
+
+// parseIssueURL parses web URLs of issues, like https://host/org/repo/issues/N.
+func (g *Gitea) parseIssueURL(s string) (org, repo string, num int, ok bool) {
+	if !strings.HasPrefix(s, g.baseURL()+"/") {
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(s, g.baseURL()+"/"), "/")
+	if len(parts) != 4 || parts[2] != "issues" {
+		return
+	}
+	num, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return
+	}
+	return parts[0], parts[1], num, true
+}
@@ -0,0 +1,129 @@
+package okrs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitea(t *testing.T) {
+	fake := &fakeREST{
+		header: "Authorization", token: "token secret",
+		next: func(w http.ResponseWriter, r *http.Request, page int) {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, page))
+		},
+	}
+	srv := newFakeREST(t, fake)
+	issue := func(repo string, num int, title, body string) map[string]interface{} {
+		return map[string]interface{}{
+			"number": num, "title": title, "body": body, "state": "open",
+			"url":      fmt.Sprintf("%s/api/v1/repos/org/%s/issues/%d", srv.URL, repo, num),
+			"html_url": fmt.Sprintf("%s/org/%s/issues/%d", srv.URL, repo, num),
+		}
+	}
+	with := func(m map[string]interface{}, k string, v interface{}) map[string]interface{} {
+		m[k] = v
+		return m
+	}
+
+	fake.pages["/api/v1/orgs/org/repos"] = []interface{}{[]map[string]interface{}{
+		{"name": "okrs"}, {"name": "team"}, {"name": "old", "archived": true},
+	}}
+	fake.pages["/api/v1/repos/org/okrs/issues"] = []interface{}{
+		[]map[string]interface{}{
+			with(issue("okrs", 1, "[P0] Grow revenue", ""), "milestone", map[string]string{"title": "2026-Q4"}),
+			with(issue("okrs", 2, "Close deals", "**Parent:** #1\n\n**Progress:** 3/10\n\n"+
+				"- [x] Call customers\n- [ ] Negotiate org/team#4\n"),
+				"assignees", []map[string]string{{"login": "alice"}}),
+		},
+		[]map[string]interface{}{
+			with(issue("okrs", 3, "Hire", "**Parent:** #1"), "state", "closed"),
+			with(issue("okrs", 4, "Fix build", ""), "pull_request", map[string]bool{"merged": false}),
+			with(issue("okrs", 5, "Spam", ""), "labels", []map[string]string{{"name": "invalid"}}),
+		},
+	}
+	fake.pages["/api/v1/repos/org/team/issues"] = []interface{}{[]map[string]interface{}{
+		with(issue("team", 1, "Onboard", "**Parent:** org/okrs#3"), "labels", []map[string]string{{"name": "P1"}}),
+		issue("team", 2, "Train", fmt.Sprintf("**Parent:** %s/org/okrs/issues/3", srv.URL)),
+		issue("team", 3, "Moderate", "**Parent:** org/okrs#5"),
+		issue("team", 4, "Negotiate", ""),
+		issue("team", 5, "Research", "**Parent:** org/ext#7"),
+	}}
+	// repository is not loaded, the parent is fetched on demand
+	fake.pages["/api/v1/repos/org/ext/issues/7"] = []interface{}{issue("ext", 7, "Expand", "")}
+
+	t.Setenv("OKRS_GITEA_TOKEN", "secret")
+	g := &Gitea{
+		URL: srv.URL, TokenEnv: "OKRS_GITEA_TOKEN",
+		Orgs: []GTOrg{{Name: "org"}},
+		Labels: GHLabels{
+			Priority: map[string]int{"P1": 1},
+			Exclude:  []string{"invalid"},
+		},
+	}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	root := tr.Root()
+	require.Len(t, root.Sub, 3)
+	okrs, team, ext := root.Sub[0], root.Sub[1], root.Sub[2]
+	require.Equal(t, Link{Title: "okrs", URL: srv.URL + "/org/okrs"}, okrs.Link)
+
+	require.Len(t, okrs.Sub, 1)
+	obj := okrs.Sub[0]
+	require.Equal(t, "Grow revenue", obj.Title)
+	require.Equal(t, pri(0), obj.Priority)
+	require.Equal(t, "2026-Q4", obj.Period)
+	require.Equal(t, Link{Title: "#1", URL: srv.URL + "/org/okrs/issues/1"}, obj.Link)
+	require.Len(t, obj.Sub, 2)
+
+	var deals, hire *Node
+	for _, n := range obj.Sub {
+		switch n.Title {
+		case "Close deals":
+			deals = n
+		case "Hire":
+			hire = n
+		}
+	}
+	require.NotNil(t, deals)
+	require.Equal(t, []string{"alice"}, deals.Owners)
+	require.Equal(t, &Progress{Done: 3, Total: 10}, deals.Progress)
+	require.Len(t, deals.Sub, 2, "checklist items must be attached")
+	require.Equal(t, "Call customers", deals.Sub[0].Title)
+	require.Equal(t, done(), deals.Sub[0].Progress)
+	require.Equal(t, Link{Title: "#4", URL: srv.URL + "/org/team/issues/4"}, deals.Sub[1].Link)
+
+	require.NotNil(t, hire)
+	require.Equal(t, StatusDone, hire.Status)
+	require.Len(t, hire.Sub, 2)
+	for _, n := range hire.Sub {
+		if n.Title == "Onboard" {
+			require.Equal(t, pri(1), n.Priority)
+		} else {
+			require.Equal(t, "Train", n.Title)
+		}
+	}
+
+	// the parent is excluded by the label filter
+	require.Len(t, team.Sub, 1)
+	require.Equal(t, "Moderate", team.Sub[0].Title)
+
+	require.Equal(t, Link{Title: "ext", URL: srv.URL + "/org/ext"}, ext.Link)
+	require.Len(t, ext.Sub, 1)
+	require.Equal(t, "Expand", ext.Sub[0].Title)
+	require.Len(t, ext.Sub[0].Sub, 1)
+	require.Equal(t, "Research", ext.Sub[0].Sub[0].Title)
+
+	fake.pages["/api/v1/repos/org/team/issues"] = []interface{}{[]map[string]interface{}{
+		issue("team", 4, "Orphan", "**Parent:** #9"),
+	}}
+	require.Error(t, g.LoadTree(context.Background(), NewTree()))
+
+	g.TokenEnv = "OKRS_NO_TOKEN"
+	require.Error(t, g.LoadTree(context.Background(), NewTree()))
+}
@@ -2,54 +2,85 @@ package okrs
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/google/go-github/github"
-	"golang.org/x/oauth2"
 )
 
 type Github struct {
-	Token string  `json:"token,omitempty" yaml:"token,omitempty"`
-	Cache string  `json:"cache,omitempty" yaml:"cache,omitempty"`
-	Orgs  []GHOrg `json:"orgs,omitempty" yaml:"orgs,omitempty"`
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// TokenEnv is the name of the environment variable with the API token.
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	// TokenFile is a path to the file with the API token.
+	TokenFile string `json:"token_file,omitempty" yaml:"token_file,omitempty"`
+	// App authenticates as a Github App installation instead of using a token.
+	App *GHApp `json:"app,omitempty" yaml:"app,omitempty"`
+	// BaseURL is the API URL of Github Enterprise Server, for example "https://github.example.com/api/v3/".
+	BaseURL string `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	// UploadURL is the upload URL of Github Enterprise Server. It's derived from BaseURL by default.
+	UploadURL string `json:"upload_url,omitempty" yaml:"upload_url,omitempty"`
+	Cache     string `json:"cache,omitempty" yaml:"cache,omitempty"`
+	// CacheTTL is the maximal age of cached responses, for example "1h". Expired responses are
+	// revalidated with conditional requests. By default, cached responses never expire.
+	CacheTTL string `json:"cache_ttl,omitempty" yaml:"cache_ttl,omitempty"`
+	// Concurrency is the maximal number of concurrent API requests. Defaults to 4.
+	Concurrency int      `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	Orgs        []GHOrg  `json:"orgs,omitempty" yaml:"orgs,omitempty"`
+	Labels      GHLabels `json:"labels,omitempty" yaml:"labels,omitempty"`
 
+	// Refresh ignores cache TTL and fetches all lists in full. Single objects are still
+	// revalidated with conditional requests.
+	Refresh bool `json:"-" yaml:"-"`
+	// Offline only uses cached responses, regardless of their age.
+	Offline bool `json:"-" yaml:"-"`
+
+	hcli *http.Client
 	cli  *github.Client
+	gql  graphQL
+	lim  ghLimiter
 	orgs map[string]*ghOrg
 }
 
 type ghOrg struct {
-	g     *Github
-	name  string
-	repos map[string]*ghRepo
+	g          *Github
+	name       string
+	repos      map[string]*ghRepo
+	projects   []*ghProject
+	projectsV2 []*ghProjectV2
 }
 
-func (org *ghOrg) loadRepo(ctx context.Context, repo string) error {
-	r, ok := org.repos[repo]
+// repo returns a repository with a given name. New repositories are hidden until loaded explicitly.
+func (org *ghOrg) repo(name string) *ghRepo {
+	r, ok := org.repos[name]
 	if !ok {
-		r = &ghRepo{org: org, name: repo}
+		r = &ghRepo{org: org, name: name, hidden: true}
 		if org.repos == nil {
 			org.repos = make(map[string]*ghRepo)
 		}
-		org.repos[repo] = r
+		org.repos[name] = r
 	}
-	return r.load(ctx)
+	return r
 }
 
 type ghRepo struct {
-	org    *ghOrg
-	name   string
-	issues map[int]*ghIssue
+	org     *ghOrg
+	name    string
+	issues  map[int]*ghIssue
+	skipped map[int]struct{} // issues excluded by the label filter
+	hidden  bool             // only loaded to resolve project cards
 }
 
 func (r *ghRepo) load(ctx context.Context) error {
+	if r.issues != nil {
+		return nil // already loaded
+	}
 	if err := r.loadIssues(ctx); err != nil {
 		return err
 	}
@@ -69,23 +100,34 @@ func (r *ghRepo) loadIssues(ctx context.Context) error {
 		return err
 	}
 	r.issues = make(map[int]*ghIssue)
+	r.skipped = make(map[int]struct{})
 	for _, is := range issues {
+		if !r.org.g.Labels.match(is.Labels) {
+			r.skipped[is.GetNumber()] = struct{}{}
+			continue
+		}
 		r.issues[is.GetNumber()] = &ghIssue{
-			repo: r, issue: is,
+			repo: r, issue: is.Issue, reason: is.StateReason,
 		}
 	}
 	return nil
 }
 
 type ghIssue struct {
-	repo  *ghRepo
-	issue *github.Issue
-	local *Tree
+	repo   *ghRepo
+	issue  *github.Issue
+	reason string // state reason of closed issues
+	local  *Tree
+
+	node *Node   // node built from this issue
+	subs []*Node // sub-issues that reference this issue as a parent
 }
 
 func (is *ghIssue) parse(ctx context.Context) error {
 	tr := NewTree()
-	err := ParseMDTree(strings.NewReader(is.issue.GetBody()), tr)
+	// generated block is derived from other issues, thus it's not parsed
+	body, _ := splitSyncBlock(is.issue.GetBody())
+	err := ParseMDTree(strings.NewReader(body), tr)
 	if err != nil {
 		return err
 	}
@@ -97,99 +139,453 @@ func (is *ghIssue) parse(ctx context.Context) error {
 }
 
 type GHOrg struct {
-	Name string `json:"name" yaml:"name"`
-	//Projects []GHProject `json:"projects,omitempty" yaml:"projects,omitempty"`
-	Repos []GHRepo `json:"repos,omitempty" yaml:"repos,omitempty"`
+	Name       string        `json:"name" yaml:"name"`
+	Projects   []GHProject   `json:"projects,omitempty" yaml:"projects,omitempty"`
+	ProjectsV2 []GHProjectV2 `json:"projects_v2,omitempty" yaml:"projects_v2,omitempty"`
+	Repos      []GHRepo      `json:"repos,omitempty" yaml:"repos,omitempty"`
 }
 
 type GHRepo struct {
 	Name string `json:"name" yaml:"name"`
 }
 
-//type GHProject struct {
-//	ID   int64  `json:"id" yaml:"id"`
-//	Name string `json:"name" yaml:"name"`
-//}
+// GHProject selects a project board of the organization by ID or by name.
+type GHProject struct {
+	ID   int64  `json:"id,omitempty" yaml:"id,omitempty"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// DoneColumns is a list of columns that mark cards as completed. Defaults to "Done".
+	DoneColumns []string `json:"done_columns,omitempty" yaml:"done_columns,omitempty"`
+}
+
+// GHLabels maps issue labels to node fields. Labels are compared case-insensitively.
+type GHLabels struct {
+	// Priority maps labels like "P1" to a priority. Priority in the issue title takes precedence.
+	Priority map[string]int `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// Status maps labels like "wontfix" to a node status. It takes precedence over the issue state.
+	Status map[string]Status `json:"status,omitempty" yaml:"status,omitempty"`
+	// Kind maps labels like "okr:objective" to a node kind.
+	Kind map[string]Kind `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Include limits repository issues to ones with at least one of these labels.
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	// Exclude skips repository issues with any of these labels.
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+// match checks if an issue with given labels passes the label filter.
+func (l GHLabels) match(labels []github.Label) bool {
+	if len(l.Include) != 0 && !hasLabel(labels, l.Include) {
+		return false
+	}
+	return !hasLabel(labels, l.Exclude)
+}
+
+// apply sets node fields from issue labels.
+func (l GHLabels) apply(nd *Node, labels []github.Label) {
+	for _, lb := range labels {
+		name := lb.GetName()
+		for k, v := range l.Priority {
+			if nd.Priority == nil && strings.EqualFold(k, name) {
+				v := v
+				nd.Priority = &v
+			}
+		}
+		for k, v := range l.Status {
+			if strings.EqualFold(k, name) {
+				nd.Status = Status(strings.ToLower(string(v)))
+			}
+		}
+		for k, v := range l.Kind {
+			if nd.Kind == "" && strings.EqualFold(k, name) {
+				nd.Kind = Kind(strings.ToLower(string(v)))
+			}
+		}
+	}
+}
 
-func (g *Github) client(ctx context.Context) *github.Client {
+func hasLabel(labels []github.Label, list []string) bool {
+	for _, lb := range labels {
+		for _, v := range list {
+			if strings.EqualFold(strings.TrimSpace(v), lb.GetName()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDone checks if cards in a given column should be marked as completed.
+func (p GHProject) isDone(col string) bool {
+	return matchAny(p.DoneColumns, "Done", col)
+}
+
+// matchAny checks if the value is in the list, ignoring case. If the list is empty, the default value is used.
+func matchAny(list []string, def string, val string) bool {
+	if len(list) == 0 {
+		list = []string{def}
+	}
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(val)) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpClient returns an authorized HTTP client. It fails if the credentials cannot be loaded.
+func (g *Github) httpClient(ctx context.Context) (*http.Client, error) {
+	if g.hcli != nil {
+		return g.hcli, nil
+	}
+	if err := g.setup(ctx); err != nil {
+		return nil, err
+	}
+	return g.hcli, nil
+}
+
+func (g *Github) client(ctx context.Context) (*github.Client, error) {
 	if g.cli != nil {
-		return g.cli
+		return g.cli, nil
+	}
+	hcli, err := g.httpClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if g.BaseURL == "" {
+		g.cli = github.NewClient(hcli)
+		return g.cli, nil
+	}
+	cli, err := github.NewEnterpriseClient(g.apiURL(), g.uploadURL(), hcli)
+	if err != nil {
+		return nil, err
+	}
+	g.cli = cli
+	return g.cli, nil
+}
+
+const (
+	ghWebURL = "https://github.com"
+	ghAPIURL = "https://api.github.com/"
+)
+
+// apiURL returns the base URL of the REST API with a trailing slash.
+func (g *Github) apiURL() string {
+	if g.BaseURL == "" {
+		return ghAPIURL
+	}
+	return strings.TrimSuffix(g.BaseURL, "/") + "/"
+}
+
+// webURL returns the URL of the web interface without a trailing slash.
+// For Github Enterprise Server, it's the host of the API URL.
+func (g *Github) webURL() string {
+	if g.BaseURL == "" {
+		return ghWebURL
 	}
-	var cli *http.Client
-	if g.Token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: g.Token},
-		)
-		cli = oauth2.NewClient(ctx, ts)
+	u, err := url.Parse(g.BaseURL)
+	if err != nil {
+		return ghWebURL
 	}
-	g.cli = github.NewClient(cli)
-	return g.cli
+	return u.Scheme + "://" + u.Host
+}
+
+func (g *Github) uploadURL() string {
+	if g.UploadURL != "" {
+		return strings.TrimSuffix(g.UploadURL, "/") + "/"
+	}
+	return g.webURL() + "/api/uploads/"
+}
+
+func (g *Github) graphQLURL() string {
+	if g.BaseURL == "" {
+		return "https://api.github.com/graphql"
+	}
+	return g.webURL() + "/api/graphql"
+}
+
+// checkConfig validates the config.
+func (g *Github) checkConfig() error {
+	if g.Refresh && g.Offline {
+		return fmt.Errorf("cannot refresh the cache in offline mode")
+	}
+	if err := checkURL("base url", g.BaseURL); err != nil {
+		return err
+	} else if err = checkURL("upload url", g.UploadURL); err != nil {
+		return err
+	}
+	_, err := parseCacheTTL(g.CacheTTL)
+	return err
+}
+
+// checkURL validates URLs of Github Enterprise Server from the config.
+func checkURL(name, s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", name, err)
+	} else if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid %s: expected an absolute URL, got %q", name, s)
+	}
+	return nil
 }
 
 func (g *Github) LoadTree(ctx context.Context, tr *Tree) error {
+	if err := g.setup(ctx); err != nil {
+		return err
+	}
 	for _, org := range g.Orgs {
-		if err := g.loadOrgTree(ctx, tr, org); err != nil {
+		if err := g.loadOrg(ctx, org); err != nil {
 			return err
 		}
 	}
+	root := tr.NewNode(Node{})
+	if err := g.asTree(ctx, tr, root); err != nil {
+		return err
+	}
+	tr.addRoot(root)
 	return nil
 }
 
-func (g *Github) loadOrgTree(ctx context.Context, tr *Tree, org GHOrg) error {
+// org returns an organization with a given name.
+func (g *Github) org(name string) *ghOrg {
 	if g.orgs == nil {
 		g.orgs = make(map[string]*ghOrg)
 	}
-	o, ok := g.orgs[org.Name]
+	o, ok := g.orgs[name]
 	if !ok {
-		o = &ghOrg{g: g, name: org.Name}
-		g.orgs[org.Name] = o
+		o = &ghOrg{g: g, name: name}
+		g.orgs[name] = o
 	}
+	return o
+}
+
+func (g *Github) loadOrg(ctx context.Context, org GHOrg) error {
+	o := g.org(org.Name)
+	var repos []*ghRepo
 	for _, repo := range org.Repos {
-		if err := o.loadRepo(ctx, repo.Name); err != nil {
+		r := o.repo(repo.Name)
+		r.hidden = false
+		repos = append(repos, r)
+	}
+	if err := g.loadRepos(ctx, repos); err != nil {
+		return err
+	}
+	for _, conf := range org.ProjectsV2 {
+		p := &ghProjectV2{org: o, conf: conf}
+		if err := p.load(ctx); err != nil {
 			return err
 		}
+		o.projectsV2 = append(o.projectsV2, p)
+	}
+	if len(org.Projects) == 0 {
+		return nil
 	}
-	nd := tr.root
-	if err := g.asTree(tr, nd); err != nil {
+	projs, err := g.listProjects(ctx, org.Name)
+	if err != nil {
 		return err
 	}
-	if len(nd.Sub) == 1 {
-		tr.root = nd.Sub[0]
+	for _, conf := range org.Projects {
+		var proj *github.Project
+		for _, p := range projs {
+			if conf.ID != 0 && p.GetID() == conf.ID {
+				proj = p.Project
+				break
+			} else if conf.ID == 0 && strings.EqualFold(p.GetName(), conf.Name) && (proj == nil || p.State == "open") {
+				// open projects are preferred over closed ones with the same name
+				proj = p.Project
+			}
+		}
+		if proj == nil {
+			name := conf.Name
+			if conf.ID != 0 {
+				name = strconv.FormatInt(conf.ID, 10)
+			}
+			return fmt.Errorf("cannot find project %q in %s", name, org.Name)
+		}
+		p := &ghProject{org: o, conf: conf, proj: proj}
+		if err := p.load(ctx); err != nil {
+			return err
+		}
+		o.projects = append(o.projects, p)
 	}
 	return nil
 }
-func (g *Github) loadProjTree(ctx context.Context, tr *Tree, org string, proj int64) (*Node, error) {
-	cols, err := g.listProjColumns(ctx, proj)
+
+type ghProject struct {
+	org  *ghOrg
+	conf GHProject
+	proj *github.Project
+	cols []ghColumn
+}
+
+type ghColumn struct {
+	name  string
+	cards []*github.ProjectCard
+}
+
+// load fetches all cards of the project, and loads repositories of issues referenced by them.
+func (p *ghProject) load(ctx context.Context) error {
+	g := p.org.g
+	cols, err := g.listProjColumns(ctx, p.proj.GetID())
 	if err != nil {
-		return nil, err
+		return err
 	}
-	root := tr.NewNode(Node{})
 	for _, col := range cols {
 		cards, err := g.listProjCards(ctx, col.GetID())
 		if err != nil {
-			return root, err
+			return err
+		}
+		p.cols = append(p.cols, ghColumn{name: col.GetName(), cards: cards})
+	}
+	var repos []*ghRepo
+	for _, col := range p.cols {
+		for _, c := range col.cards {
+			if org, repo, _, ok := g.parseIssueURL(c.GetContentURL()); ok {
+				repos = append(repos, g.org(org).repo(repo))
+			}
 		}
-		for _, c := range cards {
-			url := c.GetContentURL()
-			if url == "" && c.Note != nil {
-				root.AddChild(tr.NewNode(Node{Desc: c.GetNote()}))
+	}
+	return g.loadRepos(ctx, repos)
+}
+
+// loadRepos loads issues of multiple repositories concurrently.
+func (g *Github) loadRepos(ctx context.Context, repos []*ghRepo) error {
+	seen := make(map[*ghRepo]struct{})
+	var list []*ghRepo
+	for _, r := range repos {
+		if _, ok := seen[r]; !ok && r.issues == nil {
+			seen[r] = struct{}{}
+			list = append(list, r)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	// initialize the client before starting workers
+	if _, err := g.client(ctx); err != nil {
+		return err
+	}
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+		done  int
+	)
+	work := make(chan *ghRepo)
+	for i := 0; i < g.concurrency() && i < len(list); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				mu.Lock()
+				failed := first != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+				err := r.load(ctx)
+				mu.Lock()
+				done++
+				if err != nil && first == nil {
+					first = err
+				} else if err == nil {
+					log.Printf("loaded %d issues from %s/%s (%d/%d)", len(r.issues), r.org.name, r.name, done, len(list))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range list {
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+	return first
+}
+
+func (p *ghProject) asTree(ctx context.Context, tr *Tree) (*Node, error) {
+	g := p.org.g
+	name := p.proj.GetName()
+	root := tr.NewNode(Node{
+		Title: name, Desc: p.proj.GetBody(),
+		Link: Link{
+			Title: name, URL: fmt.Sprintf("%s/orgs/%s/projects/%d", g.webURL(), p.org.name, p.proj.GetNumber()),
+		},
+		Period: parsePeriod(name),
+	})
+	for _, col := range p.cols {
+		done := p.conf.isDone(col.name)
+		for _, c := range col.cards {
+			var nd *Node
+			if url := c.GetContentURL(); url != "" {
+				var err error
+				nd, err = g.loadByURL(ctx, tr, url)
+				if err != nil {
+					return root, err
+				}
+			} else if c.Note != nil {
+				// the first line of a note is its title
+				title, desc := c.GetNote(), ""
+				if i := strings.IndexByte(title, '\n'); i >= 0 {
+					title, desc = title[:i], title[i+1:]
+				}
+				nd = tr.NewNode(Node{Title: strings.TrimSpace(title), Desc: strings.TrimSpace(desc)})
+			}
+			if nd == nil {
 				continue
 			}
-			nd, err := g.loadByURL(ctx, tr, url)
-			if err != nil {
-				return root, err
+			if done {
+				if nd.Status == "" {
+					nd.Status = StatusDone
+				}
+				markDone(nd)
 			}
-			if nd != nil {
-				root.AddChild(nd)
+			if err := root.AddChild(nd); err != nil {
+				return root, fmt.Errorf("invalid card in project %q: %v", name, err)
 			}
 		}
 	}
 	return root, nil
 }
 
-func (g *Github) asTree(tr *Tree, root *Node) error {
+// markDone sets the progress of the node to completed.
+func markDone(n *Node) {
+	total := 1
+	if p := n.Progress; p != nil && p.Total > 0 {
+		total = p.Total
+	}
+	n.Progress = &Progress{Done: total, Total: total}
+}
+
+func (g *Github) asTree(ctx context.Context, tr *Tree, root *Node) error {
+	// index issues from all repositories first, so parent links can reference issues from other repositories
+	idx := newGHIndex(g, tr)
+	var repos []*ghRepo
+	for _, org := range g.orgs {
+		for _, r := range org.repos {
+			repos = append(repos, r)
+		}
+	}
+	for _, r := range repos {
+		r.buildNodes(idx)
+	}
+	if err := idx.resolveParents(ctx); err != nil {
+		return err
+	}
+	for _, r := range repos {
+		if err := r.resolveLocal(ctx, idx); err != nil {
+			return err
+		}
+	}
+	if err := idx.resolveParents(ctx); err != nil {
+		return err
+	}
+
+	// build trees for all repositories, so project cards can reference issue nodes
+	nodes := make(map[*ghOrg]*Node, len(g.orgs))
 	for _, org := range g.orgs {
-		u := fmt.Sprintf("https://github.com/%s", org.name)
+		u := fmt.Sprintf("%s/%s", g.webURL(), org.name)
 		nd := tr.NewNode(Node{
 			Title: org.name,
 			Link: Link{
@@ -200,10 +596,48 @@ func (g *Github) asTree(tr *Tree, root *Node) error {
 		if err := org.asTree(tr, nd); err != nil {
 			return err
 		}
-		if len(nd.Sub) == 1 {
-			root.AddChild(nd.Sub[0])
-		} else {
-			root.AddChild(nd)
+		nodes[org] = nd
+	}
+	for org, nd := range nodes {
+		for _, p := range org.projects {
+			pn, err := p.asTree(ctx, tr)
+			if err != nil {
+				return err
+			}
+			if err = nd.AddChild(pn); err != nil {
+				return err
+			}
+		}
+		for _, p := range org.projectsV2 {
+			pn, err := p.asTree(ctx, tr)
+			if err != nil {
+				return err
+			}
+			if err = nd.AddChild(pn); err != nil {
+				return err
+			}
+		}
+		switch len(nd.Sub) {
+		case 0:
+			// only has hidden repositories
+		case 1:
+			if err := root.AddChild(nd.Sub[0]); err != nil {
+				return err
+			}
+		default:
+			nd.Sort()
+			if err := root.AddChild(nd); err != nil {
+				return err
+			}
+		}
+	}
+	// issues fetched on demand that are not linked to any other issue
+	for _, nd := range idx.extra {
+		markOpen(nd)
+		if nd.parent == nil {
+			if err := root.AddChild(nd); err != nil {
+				return err
+			}
 		}
 	}
 	root.Sort()
@@ -212,7 +646,7 @@ func (g *Github) asTree(tr *Tree, root *Node) error {
 
 func (org *ghOrg) asTree(tr *Tree, root *Node) error {
 	for _, repo := range org.repos {
-		u := fmt.Sprintf("https://github.com/%s/%s", org.name, repo.name)
+		u := fmt.Sprintf("%s/%s/%s", org.g.webURL(), org.name, repo.name)
 		nd := tr.NewNode(Node{
 			Title: repo.name,
 			Link: Link{
@@ -223,186 +657,330 @@ func (org *ghOrg) asTree(tr *Tree, root *Node) error {
 		if err := repo.asTree(tr, nd); err != nil {
 			return err
 		}
-		root.AddChild(nd)
+		if !repo.hidden {
+			if err := root.AddChild(nd); err != nil {
+				return err
+			}
+		}
 	}
 	root.Sort()
 	return nil
 }
 
-func (r *ghRepo) asTree(tr *Tree, root *Node) error {
-	byNum := make(map[string]*Node)
-	byID := make(map[string]*Node) // TODO: should be global?
-	byURL := make(map[string]*Node)
-	byTitle := make(map[string]*Node)
-	nodes := make(map[*ghIssue]*Node)
+// ghIndex resolves references to issues across all loaded repositories.
+type ghIndex struct {
+	g      *Github
+	tr     *Tree
+	byKey  map[string]*Node   // "org/repo#N"
+	byURL  map[string]*Node   // API and web URLs
+	byNode map[*Node]*ghIssue // issues from loaded repositories
+	queue  []*ghIssue         // issues with unresolved parent links
+	extra  []*Node            // issues fetched on demand from repositories that are not loaded
+}
 
-	// build indexes
-	for _, is := range r.issues {
-		id := is.issue.GetURL()
-		url := is.issue.GetHTMLURL()
-		ref := fmt.Sprintf("#%d", is.issue.GetNumber())
-		title := is.issue.GetTitle()
+func newGHIndex(g *Github, tr *Tree) *ghIndex {
+	return &ghIndex{
+		g: g, tr: tr,
+		byKey:  make(map[string]*Node),
+		byURL:  make(map[string]*Node),
+		byNode: make(map[*Node]*ghIssue),
+	}
+}
 
-		nd := tr.NewNode(Node{
-			ID: id, Title: title,
-			Link: Link{
-				Title: ref, URL: url,
-			},
-		})
+func issueKey(org, repo string, num int) string {
+	return fmt.Sprintf("%s/%s#%d", org, repo, num)
+}
 
-		byID[id] = nd
-		byURL[url] = nd
-		byNum[ref] = nd
-		byTitle[title] = nd
-		nodes[is] = nd
+var (
+	reLocalRef = regexp.MustCompile(`^#(\d+)$`)
+	reRepoRef  = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+)
 
-		// get parent info from local tree
-		local := is.local.Root()
-		if local == nil {
-			continue
-		}
-		if nd.parent == nil {
-			nd.parent = local.parent
-		}
+// parseRef resolves a link to an issue, relative to a given repository.
+func (r *ghRepo) parseRef(l Link) (org, repo string, num int, ok bool) {
+	if sub := reLocalRef.FindStringSubmatch(l.Title); len(sub) != 0 {
+		num, _ = strconv.Atoi(sub[1])
+		return r.org.name, r.name, num, true
+	} else if sub = reRepoRef.FindStringSubmatch(l.Title); len(sub) != 0 {
+		num, _ = strconv.Atoi(sub[3])
+		return sub[1], sub[2], num, true
 	}
+	return r.org.g.parseIssueURL(l.URL)
+}
 
-	byLink := func(p Link) *Node {
-		if is, ok := byNum[p.Title]; ok {
-			return is
-		} else if is, ok := byID[p.URL]; ok {
-			return is
-		} else if is, ok := byURL[p.URL]; ok {
-			return is
-		}
-		return nil
+// add registers the issue node in the index.
+func (idx *ghIndex) add(org, repo string, num int, nd *Node) {
+	idx.byKey[issueKey(org, repo, num)] = nd
+	if nd.ID != "" {
+		idx.byURL[nd.ID] = nd
 	}
-
-	// resolve all parent links
-	parents := make(map[*Node]*Node)
-	for _, is := range r.issues {
-		nd := nodes[is]
-		p := nd.parent
-		if p == nil {
-			continue
-		}
-		par := byLink(*p)
-		if par == nil {
-			return fmt.Errorf("cannot find parent: %+v", p)
-		}
-		par.AddChild(nd)
-		parents[nd] = par
+	if nd.Link.URL != "" {
+		idx.byURL[nd.Link.URL] = nd
 	}
+}
 
-	// resolve local trees
-	var resolve func(root *Node, local *Node) error
-	resolve = func(root *Node, local *Node) error {
-		if root.Priority == nil {
-			root.Priority = local.Priority
-		}
-		if root.Progress == nil {
-			root.Progress = local.Progress
-		}
-		if root.Desc == "" {
-			root.Desc = local.Desc
-		}
-		root.Links = append(root.Links, local.Links...)
+// lookup finds a node for the linked issue. If the issue is from a repository that is not loaded, it's fetched on demand.
+func (idx *ghIndex) lookup(ctx context.Context, from *ghRepo, l Link) (*Node, error) {
+	org, repo, num, ok := from.parseRef(l)
+	if !ok {
+		return idx.byURL[l.URL], nil
+	}
+	if nd, ok := idx.byKey[issueKey(org, repo, num)]; ok {
+		return nd, nil
+	}
+	if r := idx.g.orgs[org]; r != nil && r.repos[repo] != nil && r.repos[repo].issues != nil {
+		return nil, nil // repository is loaded, but the issue is not there
+	}
+	log.Printf("fetching %s", issueKey(org, repo, num))
+	iss, err := idx.g.getIssue(ctx, org, repo, num)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch %s: %v", issueKey(org, repo, num), err)
+	}
+	is, err := idx.g.standaloneIssue(ctx, idx.tr, org, repo, iss.Issue, iss.StateReason)
+	if err != nil {
+		return nil, err
+	}
+	nd := is.node
+	idx.add(org, repo, num, nd)
+	idx.extra = append(idx.extra, nd)
+	if local := is.local.Root(); local != nil && local.parent != nil {
+		idx.queue = append(idx.queue, is)
+	}
+	return nd, nil
+}
 
-		for _, s := range local.Sub {
-			l := s.Link
+// buildNodes creates nodes for all issues of the repository and adds them to the index.
+func (r *ghRepo) buildNodes(idx *ghIndex) {
+	for num, is := range r.issues {
+		nd := is.asNode(idx.tr)
+		is.node, is.subs = nd, nil
+		idx.add(r.org.name, r.name, num, nd)
+		idx.byNode[nd] = is
 
-			sn := byLink(l)
-			create := sn == nil
-			if create {
-				// no link for this issue - create a new node
-				// TODO: try to match by title?
-				// FIXME: this issue might be from different repo
-				snc := *s
-				snc.Sub = nil
-				sn = &snc
+		// get parent info from local tree
+		if local := is.local.Root(); local != nil && local.parent != nil {
+			idx.queue = append(idx.queue, is)
+		}
+	}
+}
 
-				if l.URL != "" {
-					byURL[l.URL] = sn
-					byID[l.URL] = sn // TODO: should try to decode URL and convert to id
-				}
-				if l.Title != "" {
-					if reHashRef.MatchString(l.Title) {
-						byNum[l.Title] = sn
-					}
-					byTitle[l.Title] = sn
-				}
-			}
-			l = sn.Link
-
-			if l.Title == "" && l.URL != "" {
-				if u, err := url.Parse(l.URL); err == nil && u.Host == "github.com" {
-					sub := strings.Split(strings.Trim(u.Path, "/"), "/")
-					if len(sub) == 4 && sub[2] == "issues" || sub[2] == "pull" {
-						org, repo, num := sub[0], sub[1], sub[3]
-						if org == r.org.name && repo == r.name {
-							l.Title = "#" + num
-						} else {
-							l.Title = fmt.Sprintf("%s/%s#%s", org, repo, num)
-						}
-					} else {
-						log.Println("missing title for the link:", sub)
-					}
-				}
-				sn.Link = l
+// resolveParents links all issues in the queue to their parents, including parents from other repositories.
+func (idx *ghIndex) resolveParents(ctx context.Context) error {
+	for len(idx.queue) != 0 {
+		is := idx.queue[0]
+		idx.queue = idx.queue[1:]
+		r, nd := is.repo, is.node
+		p := *is.local.Root().parent
+		par, err := idx.lookup(ctx, r, p)
+		if err != nil {
+			return err
+		} else if par == nil {
+			if org, repo, num, ok := r.parseRef(p); ok && idx.g.isSkipped(org, repo, num) {
+				// parent is excluded by the label filter - keep the issue at the top level
+				continue
 			}
-
-			if err := resolve(sn, s); err != nil {
+			err = fmt.Errorf("cannot find parent of %s/%s#%d: %+v", r.org.name, r.name, is.issue.GetNumber(), p)
+			if err = idx.tr.loadError(RuleUnresolvedParent, nd, err); err != nil {
 				return err
 			}
-			if sn.parent != nil {
-				l1, l2 := *sn.parent, root.Link
-				if l1.Title == l2.Title && reHashRef.MatchString(l1.Title) {
-					l1 = l2
-					sn.parent = &l1
-				}
-				if l1.URL != l2.URL {
-					return fmt.Errorf("incorrect parent of %v: %v (from parent link in %v) vs %v (from local subtree of %v)",
-						sn.Link.Title, l1.Title, sn.Link.Title, l2.Title, l2.Title)
+			continue
+		}
+		if l := nd.parent; l != nil {
+			// already listed in the body of another issue
+			if l.URL != par.Link.URL {
+				err = fmt.Errorf("incorrect parent of %s/%s#%d: %v (from parent link) vs %v (from local subtree)",
+					r.org.name, r.name, is.issue.GetNumber(), par.Link.Title, l.Title)
+				if err = idx.tr.loadError(RuleConflictingParent, nd, err); err != nil {
+					return err
 				}
+				continue
+			}
+		} else if err := par.AddChild(nd); err != nil {
+			err = fmt.Errorf("invalid parent link in %s/%s: %v", r.org.name, r.name, err)
+			if err = idx.tr.loadError(RuleCycle, nd, err); err != nil {
+				return err
 			}
-			par := root.Link
-			sn.parent = &par
-
-			if op, ok := parents[sn]; !ok {
-				parents[sn] = root
-				root.AddChild(sn)
-			} else {
-				// TODO: use order in the local tree
-				if op != root {
-					return fmt.Errorf("unexpected root for %v: exp %v, got %v",
-						sn.Link, root.Link, op.Link)
+			continue
+		} else {
+			l := par.Link
+			nd.parent = &l
+		}
+		if pis := idx.byNode[par]; pis != nil {
+			pis.subs = append(pis.subs, nd)
+		}
+	}
+	return nil
+}
+
+// resolveLocal merges trees parsed from issue bodies into issue nodes.
+func (r *ghRepo) resolveLocal(ctx context.Context, idx *ghIndex) error {
+	find := func(s *Node) (*Node, error) {
+		l := s.Link
+		sn, err := idx.lookup(ctx, r, l)
+		if err != nil || sn != nil {
+			return sn, err
+		}
+		org, repo, num, ok := r.parseRef(l)
+		if ok && idx.g.isSkipped(org, repo, num) {
+			return nil, nil
+		}
+		// no link for this issue - create a new node
+		// TODO: try to match by title?
+		sn = localNode(idx.tr, s)
+		if ok {
+			idx.byKey[issueKey(org, repo, num)] = sn
+		}
+		if l.URL != "" {
+			idx.byURL[l.URL] = sn
+		}
+		if l.Title == "" && l.URL != "" {
+			if org, repo, num, ok := r.org.g.parseIssueURL(l.URL); ok {
+				if org == r.org.name && repo == r.name {
+					sn.Link.Title = fmt.Sprintf("#%d", num)
+				} else {
+					sn.Link.Title = issueKey(org, repo, num)
 				}
+			} else if r.org.g.isWebURL(l.URL) {
+				log.Println("missing title for the link:", l.URL)
 			}
-			// TODO: merge
-			root.Sort()
 		}
-		return nil
+		return sn, nil
 	}
 	for _, is := range r.issues {
 		local := is.local.Root()
 		if local == nil {
 			continue
 		}
-		nd := nodes[is]
-		if err := resolve(nd, local); err != nil {
+		mergeLocal(is.node, local)
+		if err := attachLocal(idx.tr, is.node, local, find); err != nil {
 			return err
 		}
+		is.node.Sort()
 	}
+	return nil
+}
 
-	// add all root nodes to parent node
+// asTree adds all top-level issues of the repository to the root node.
+// Issue nodes must be resolved with the index first.
+func (r *ghRepo) asTree(tr *Tree, root *Node) error {
 	for _, is := range r.issues {
-		nd := nodes[is]
+		nd := is.node
+		markOpen(nd)
 		if nd.parent == nil {
-			root.AddChild(nd)
+			if err := root.AddChild(nd); err != nil {
+				return fmt.Errorf("invalid issue in %s/%s: %v", r.org.name, r.name, err)
+			}
 		}
 	}
 	return nil
 }
 
+// asNode creates a node for the issue. Fields from the issue body are not included.
+func (is *ghIssue) asNode(tr *Tree) *Node {
+	title, pri := issueTitle(is.issue.GetTitle())
+	nd := tr.NewNode(Node{
+		ID: is.issue.GetURL(), Title: title,
+		Link: Link{
+			Title: fmt.Sprintf("#%d", is.issue.GetNumber()),
+			URL:   is.issue.GetHTMLURL(),
+		},
+		Priority: pri,
+		Owners:   issueOwners(is.issue),
+	})
+	if m := is.issue.Milestone; m != nil {
+		nd.Period = parsePeriod(m.GetTitle())
+		nd.Due = m.DueOn
+	}
+	if is.issue.GetState() == "closed" {
+		if is.reason == "not_planned" {
+			nd.Status = StatusCanceled
+		} else {
+			nd.Status = StatusDone
+		}
+	}
+	is.repo.org.g.Labels.apply(nd, is.issue.Labels)
+	if nd.Status == StatusDone {
+		markDone(nd)
+	}
+	return nd
+}
+
+// markOpen sets the progress of an open issue without sub-issues to not done.
+// Otherwise, it would be considered done, since it has no progress information.
+func markOpen(n *Node) {
+	if n.Status == "" && n.Progress == nil && n.Metric == nil && len(n.Sub) == 0 {
+		n.Progress = &Progress{Done: 0, Total: 1}
+	}
+}
+
+// mergeLocal fills fields of the issue node from the tree parsed from the issue body.
+func mergeLocal(root, local *Node) {
+	if root.Priority == nil {
+		root.Priority = local.Priority
+	}
+	if root.Progress == nil {
+		root.Progress = local.Progress
+	}
+	if root.Desc == "" {
+		root.Desc = local.Desc
+	}
+	if root.Metric == nil {
+		root.Metric = local.Metric
+	}
+	if root.Weight == nil {
+		root.Weight = local.Weight
+	}
+	if root.Team == "" {
+		root.Team = local.Team
+	}
+	if root.Status == "" {
+		root.Status = local.Status
+	}
+	if root.Kind == "" {
+		root.Kind = local.Kind
+	}
+	root.Owners = appendOwners(root.Owners, local.Owners...)
+	if root.Period == "" {
+		root.Period = local.Period
+	}
+	if root.Start == nil {
+		root.Start = local.Start
+	}
+	if root.Due == nil {
+		root.Due = local.Due
+	}
+	root.Links = append(root.Links, local.Links...)
+}
+
+// issueTitle strips the "[P1]" priority prefix from the issue title.
+//
+// Publish encodes priorities of markdown nodes this way, thus issue titles must be parsed
+// the same way as markdown headers for published issues to load back with the same priority.
+// The prefix is only recognized at the beginning of the title.
+func issueTitle(s string) (string, *int) {
+	sub := rePriority.FindStringSubmatchIndex(s)
+	if len(sub) == 0 || strings.TrimSpace(s[:sub[0]]) != "" {
+		return s, nil
+	}
+	pr, err := strconv.Atoi(s[sub[2]:sub[3]])
+	if err != nil {
+		return s, nil
+	}
+	return s[sub[1]:], &pr
+}
+
+// issueOwners returns logins of all users assigned to the issue.
+func issueOwners(is *github.Issue) []string {
+	var out []string
+	for _, u := range is.Assignees {
+		out = appendOwners(out, u.GetLogin())
+	}
+	if u := is.Assignee; u != nil && len(out) == 0 {
+		out = appendOwners(out, u.GetLogin())
+	}
+	return out
+}
+
 func (g *Github) loadByURL(ctx context.Context, tr *Tree, url string) (*Node, error) {
 	if strings.Contains(url, "/issues/") {
 		return g.loadIssueTreeByURL(ctx, tr, url)
@@ -410,33 +988,99 @@ func (g *Github) loadByURL(ctx context.Context, tr *Tree, url string) (*Node, er
 	log.Println("unknown url format:", url)
 	return nil, nil
 }
+
+// parseIssueURL extracts the issue number from API or web URL of the issue or pull request.
+// URLs that point to other hosts are rejected.
+func (g *Github) parseIssueURL(s string) (org, repo string, num int, ok bool) {
+	u, err := url.Parse(s)
+	if err != nil || (!g.isWebURL(s) && !sameHost(u, g.apiURL())) {
+		return "", "", 0, false
+	}
+	sub := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if n := len(sub); n >= 5 && sub[n-5] == "repos" {
+		sub = sub[n-4:] // api.github.com/repos/<org>/<repo>/issues/<num> or <host>/api/v3/repos/...
+	}
+	if len(sub) != 4 || (sub[2] != "issues" && sub[2] != "pull" && sub[2] != "pulls") {
+		return "", "", 0, false
+	}
+	num, err = strconv.Atoi(sub[3])
+	if err != nil {
+		return "", "", 0, false
+	}
+	return sub[0], sub[1], num, true
+}
+
+// isWebURL checks if the URL points to the web interface of Github.
+func (g *Github) isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && sameHost(u, g.webURL())
+}
+
+func sameHost(u *url.URL, base string) bool {
+	b, err := url.Parse(base)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, b.Host)
+}
+
 func (g *Github) loadIssueTreeByURL(ctx context.Context, tr *Tree, url string) (*Node, error) {
-	i := strings.Index(url, "/repos/")
-	i += 1 + len("repos/")
-	url = strings.Trim(url[i:], "/")
-	sub := strings.Split(url, "/")
-	var org, repo, nums string
-	switch len(sub) {
-	case 4:
-		org, repo, nums = sub[0], sub[1], sub[3]
-	case 5: // github.com/<org>/<repo>/issues/<num>
-		if sub[0] != "github.com" || sub[3] != "issues" {
-			log.Printf("unexpected url: %s", url)
-			return tr.NewNode(Node{Link: Link{URL: url}}), nil
-		}
-		org, repo, nums = sub[1], sub[2], sub[4]
-	default:
+	org, repo, num, ok := g.parseIssueURL(url)
+	if !ok {
 		log.Printf("unexpected url: %s", url)
 		return tr.NewNode(Node{Link: Link{URL: url}}), nil
 	}
-	num, err := strconv.Atoi(nums)
+	// reuse the node if the issue was loaded with the repository
+	if is := g.loadedIssue(org, repo, num); is != nil && is.node != nil {
+		return is.node, nil
+	}
+	return g.loadIssueTreeByNum(ctx, tr, org, repo, num)
+}
+
+// loadIssueTreeByNum loads a single issue that is not listed in the repository (for example, closed one).
+func (g *Github) loadIssueTreeByNum(ctx context.Context, tr *Tree, org, repo string, num int) (*Node, error) {
+	iss, err := g.getIssue(ctx, org, repo, num)
+	if err != nil {
+		return nil, err
+	}
+	is, err := g.standaloneIssue(ctx, tr, org, repo, iss.Issue, iss.StateReason)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse issues number %s: %v", nums, err)
+		return nil, err
+	}
+	markOpen(is.node)
+	return is.node, nil
+}
+
+// loadedIssue returns an issue from one of the loaded repositories.
+func (g *Github) loadedIssue(org, repo string, num int) *ghIssue {
+	if o := g.orgs[org]; o != nil {
+		if r := o.repos[repo]; r != nil {
+			return r.issues[num]
+		}
+	}
+	return nil
+}
+
+// isSkipped checks if the issue was excluded from one of the loaded repositories by the label filter.
+func (g *Github) isSkipped(org, repo string, num int) bool {
+	if o := g.orgs[org]; o != nil {
+		if r := o.repos[repo]; r != nil {
+			_, ok := r.skipped[num]
+			return ok
+		}
 	}
-	_ = num
-	_, _ = org, repo
-	panic("not implemented") // FIXME
-	//return g.loadIssueTreeByNum(ctx, tr, org, repo, num)
+	return false
+}
+
+// standaloneIssue builds a node for an issue that is not a part of a loaded repository.
+// Only fields from the issue body are used, sub-issues are not resolved.
+func (g *Github) standaloneIssue(ctx context.Context, tr *Tree, org, repo string, iss *github.Issue, reason string) (*ghIssue, error) {
+	is := &ghIssue{repo: g.org(org).repo(repo), issue: iss, reason: reason}
+	if err := is.parse(ctx); err != nil {
+		return nil, err
+	}
+	is.node = is.asNode(tr)
+	if local := is.local.Root(); local != nil {
+		mergeLocal(is.node, local)
+	}
+	return is, nil
 }
 func (g *Github) parseLinks(ctx context.Context, org, repo, str string) (string, []string, error) {
 	var links []string
@@ -456,149 +1100,73 @@ func (g *Github) parseLinks(ctx context.Context, org, repo, str string) (string,
 	return str, links, nil
 }
 
-func (g *Github) cacheDir() string {
-	return g.Cache
-}
-func (g *Github) cachePath(key string) string {
-	return filepath.Join(g.cacheDir(), "gh_"+key+".json")
+// issueData is an issue with fields that are not supported by the github package.
+type issueData struct {
+	*github.Issue
+	// StateReason is set for closed issues. It's either "completed" or "not_planned".
+	StateReason string `json:"state_reason,omitempty"`
 }
-func (g *Github) fromCache(key string, out interface{}) bool {
-	if g.cacheDir() == "" {
-		return false
-	}
-	f, err := os.Open(g.cachePath(key))
-	if err != nil {
-		if !os.IsNotExist(err) {
-			log.Println(err)
-		}
-		return false
-	}
-	defer f.Close()
-	err = json.NewDecoder(f).Decode(out)
-	if err != nil {
-		log.Println(err)
-		return false
-	}
-	return true
-}
-func (g *Github) writeCache(key string, data interface{}) {
-	if g.cacheDir() == "" {
-		return
-	}
-	_ = os.MkdirAll(g.cacheDir(), 0755)
-	f, err := os.Create(g.cachePath(key))
-	if err != nil {
-		return
-	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(data)
-}
-func (g *Github) getIssue(ctx context.Context, org, repo string, num int) (*github.Issue, error) {
+
+func (g *Github) getIssue(ctx context.Context, org, repo string, num int) (*issueData, error) {
 	key := fmt.Sprintf("%s_%s_issue_%v", org, repo, num)
-	var iss *github.Issue
-	if g.fromCache(key, &iss) {
-		return iss, nil
-	}
-	gh := g.client(ctx)
-	iss, _, err := gh.Issues.Get(ctx, org, repo, num)
-	if err == nil {
-		g.writeCache(key, iss)
+	var iss *issueData
+	if err := g.getCached(ctx, key, fmt.Sprintf("repos/%s/%s/issues/%d", org, repo, num), &iss); err != nil {
+		return nil, err
 	}
-	return iss, err
+	return iss, nil
+}
+
+// ghProjectState is a project with its state, which is missing in the github package.
+type ghProjectState struct {
+	*github.Project
+	State string `json:"state,omitempty"`
 }
-func (g *Github) listProjects(ctx context.Context, org string) ([]*github.Project, error) {
+
+// listProjects lists open and closed projects of the organization, so closed boards can be selected as well.
+func (g *Github) listProjects(ctx context.Context, org string) ([]ghProjectState, error) {
 	key := fmt.Sprintf("%s_projects", org)
-	var out []*github.Project
-	if g.fromCache(key, &out) {
-		return out, nil
-	}
-	gh := g.client(ctx)
-	for page := 1; ; page++ {
-		buf, _, err := gh.Organizations.ListProjects(ctx, org, &github.ProjectListOptions{
-			State: "open",
-			ListOptions: github.ListOptions{
-				Page: page, PerPage: 100,
-			},
-		})
-		out = append(out, buf...)
-		if err != nil {
-			return out, err
-		} else if len(buf) == 0 {
-			break
-		}
-	}
-	g.writeCache(key, out)
-	return out, nil
+	var out []ghProjectState
+	err := g.listCached(ctx, key, fmt.Sprintf("orgs/%s/projects?state=all", org), ghProjectsPreview, false, &out)
+	return out, err
 }
 func (g *Github) listProjColumns(ctx context.Context, proj int64) ([]*github.ProjectColumn, error) {
 	key := fmt.Sprintf("project_%d_col", proj)
 	var out []*github.ProjectColumn
-	if g.fromCache(key, &out) {
-		return out, nil
-	}
-	gh := g.client(ctx)
-	for page := 1; ; page++ {
-		buf, _, err := gh.Projects.ListProjectColumns(ctx, proj, &github.ListOptions{
-			Page: page, PerPage: 100,
-		})
-		out = append(out, buf...)
-		if err != nil {
-			return out, err
-		} else if len(buf) == 0 {
-			break
-		}
-	}
-	g.writeCache(key, out)
-	return out, nil
+	err := g.listCached(ctx, key, fmt.Sprintf("projects/%d/columns", proj), ghProjectsPreview, false, &out)
+	return out, err
 }
 func (g *Github) listProjCards(ctx context.Context, col int64) ([]*github.ProjectCard, error) {
 	key := fmt.Sprintf("project_cards_%v", col)
 	var out []*github.ProjectCard
-	if g.fromCache(key, &out) {
-		return out, nil
-	}
-	gh := g.client(ctx)
-	for page := 1; ; page++ {
-		buf, _, err := gh.Projects.ListProjectCards(ctx, col, &github.ListOptions{
-			Page: page, PerPage: 100,
-		})
-		out = append(out, buf...)
-		if err != nil {
-			return out, err
-		} else if len(buf) == 0 {
-			break
-		}
-	}
-	g.writeCache(key, out)
-	return out, nil
+	err := g.listCached(ctx, key, fmt.Sprintf("projects/columns/%d/cards", col), ghProjectsPreview, false, &out)
+	return out, err
 }
-func (g *Github) listIssues(ctx context.Context, org, repo string) ([]*github.Issue, error) {
-	key := fmt.Sprintf("%s_%s_issues", org, repo)
-	var out []*github.Issue
-	if g.fromCache(key, &out) {
-		return out, nil
-	}
-	gh := g.client(ctx)
-	for page := 1; ; page++ {
-		buf, _, err := gh.Issues.ListByRepo(ctx, org, repo, &github.IssueListByRepoOptions{
-			ListOptions: github.ListOptions{
-				Page: page, PerPage: 100,
-			},
-		})
-		out = append(out, buf...)
-		if err != nil {
-			return out, err
-		} else if len(buf) == 0 {
-			break
+
+// issuesKey returns a cache key for the list of issues of the repository.
+func issuesKey(org, repo string) string {
+	return fmt.Sprintf("%s_%s_issues_all", org, repo)
+}
+
+// listIssues returns both open and closed issues of the repository.
+func (g *Github) listIssues(ctx context.Context, org, repo string) ([]*issueData, error) {
+	var out []*issueData
+	// requested directly, since the github package drops the state reason;
+	// recently updated issues go first, so any change is visible on the first page
+	u := fmt.Sprintf("repos/%s/%s/issues?state=all&sort=updated&direction=desc", org, repo)
+	err := g.listCached(ctx, issuesKey(org, repo), u, "", true, &out)
+	// the API lists pull requests as issues as well
+	issues := out[:0]
+	for _, is := range out {
+		if is.PullRequestLinks == nil {
+			issues = append(issues, is)
 		}
 	}
-	g.writeCache(key, out)
-	return out, nil
+	return issues, err
 }
 func (g *Github) eachIssue(ctx context.Context, org, repo string, fnc func(*github.Issue) bool) error {
 	list, err := g.listIssues(ctx, org, repo)
 	for _, is := range list {
-		if !fnc(is) {
+		if !fnc(is.Issue) {
 			break
 		}
 	}
@@ -0,0 +1,228 @@
+package okrs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gopkg.in/yaml.v2"
+)
+
+// GHApp configures authentication as a Github App installation.
+type GHApp struct {
+	ID             int64 `json:"id" yaml:"id"`
+	InstallationID int64 `json:"installation_id" yaml:"installation_id"`
+	// PrivateKeyFile is a path to the PEM-encoded private key of the app.
+	PrivateKeyFile string `json:"private_key_file,omitempty" yaml:"private_key_file,omitempty"`
+	// PrivateKeyEnv is the name of the environment variable with the PEM-encoded private key.
+	PrivateKeyEnv string `json:"private_key_env,omitempty" yaml:"private_key_env,omitempty"`
+}
+
+// setup validates the config and prepares an authenticated HTTP client.
+func (g *Github) setup(ctx context.Context) error {
+	if err := g.checkConfig(); err != nil {
+		return err
+	}
+	if g.hcli != nil {
+		return nil // already configured
+	}
+	ts, err := g.tokenSource()
+	if err != nil {
+		return err
+	}
+	g.hcli = http.DefaultClient
+	if ts != nil {
+		g.hcli = oauth2.NewClient(ctx, ts)
+	}
+	return nil
+}
+
+// tokenSource returns a source of API tokens. The token is selected in the following order:
+// the token from the config, Github App, the environment variable, the token file, and
+// finally the same sources the gh CLI uses. It returns nil if no token is found.
+func (g *Github) tokenSource() (oauth2.TokenSource, error) {
+	static := func(tok string) oauth2.TokenSource {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tok})
+	}
+	switch {
+	case g.Token != "":
+		return static(g.Token), nil
+	case g.App != nil:
+		return g.App.tokenSource(g.apiURL())
+	case g.TokenEnv != "":
+		tok, err := envToken(g.TokenEnv)
+		if err != nil {
+			return nil, err
+		}
+		return static(tok), nil
+	case g.TokenFile != "":
+		data, err := ioutil.ReadFile(g.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token: %v", err)
+		}
+		tok := strings.TrimSpace(string(data))
+		if tok == "" {
+			return nil, fmt.Errorf("token file %s is empty", g.TokenFile)
+		}
+		return static(tok), nil
+	}
+	host := "github.com"
+	if u, err := url.Parse(g.webURL()); err == nil {
+		host = u.Host
+	}
+	if tok := ghCLIToken(host); tok != "" {
+		return static(tok), nil
+	}
+	return nil, nil
+}
+
+// ghCLIToken returns a token for the host the same way the gh CLI does: from environment variables or hosts.yml.
+// Tokens stored by gh in the system keyring are not supported.
+func ghCLIToken(host string) string {
+	envs := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != "github.com" {
+		envs = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, name := range envs {
+		if tok := os.Getenv(name); tok != "" {
+			return tok
+		}
+	}
+	dir := os.Getenv("GH_CONFIG_DIR")
+	if dir == "" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			dir = filepath.Join(xdg, "gh")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gh")
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	var hosts map[string]struct {
+		Token string `yaml:"oauth_token"`
+	}
+	if err = yaml.Unmarshal(data, &hosts); err != nil {
+		return ""
+	}
+	return hosts[host].Token
+}
+
+// tokenSource returns a source of installation tokens. Tokens are refreshed when they expire.
+func (a *GHApp) tokenSource(api string) (oauth2.TokenSource, error) {
+	if a.ID == 0 || a.InstallationID == 0 {
+		return nil, fmt.Errorf("github app: both id and installation_id must be set")
+	}
+	var data []byte
+	switch {
+	case a.PrivateKeyEnv != "":
+		data = []byte(os.Getenv(a.PrivateKeyEnv))
+		if len(data) == 0 {
+			return nil, fmt.Errorf("github app: environment variable %s is not set", a.PrivateKeyEnv)
+		}
+	case a.PrivateKeyFile != "":
+		var err error
+		data, err = ioutil.ReadFile(a.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("github app: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("github app: private key is not set")
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("github app: %v", err)
+	}
+	return oauth2.ReuseTokenSource(nil, &ghAppTokens{app: a, key: key, api: api}), nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM-encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected RSA private key, got %T", key)
+	}
+	return rsaKey, nil
+}
+
+// ghAppTokens requests installation tokens of the Github App.
+type ghAppTokens struct {
+	app *GHApp
+	key *rsa.PrivateKey
+	api string
+}
+
+// jwt returns a short-lived token that authenticates the app itself.
+func (t *ghAppTokens) jwt(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		// allow some clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": t.app.ID,
+	})
+	msg := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(msg))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return msg + "." + enc.EncodeToString(sig), nil
+}
+
+func (t *ghAppTokens) Token() (*oauth2.Token, error) {
+	jwt, err := t.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%sapp/installations/%d/access_tokens", t.api, t.app.InstallationID)
+	req, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot get installation token: %s", resp.Status)
+	}
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: out.Token, Expiry: out.ExpiresAt}, nil
+}
@@ -0,0 +1,138 @@
+package okrs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenSource(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GH_CONFIG_DIR", dir)
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("OKRS_TOKEN", "env-token\n")
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	for _, c := range []struct {
+		name  string
+		g     *Github
+		hosts string
+		exp   string
+		err   bool
+	}{
+		{name: "none", g: &Github{}},
+		{name: "inline", g: &Github{Token: "inline", TokenEnv: "OKRS_TOKEN"}, exp: "inline"},
+		{name: "env", g: &Github{TokenEnv: "OKRS_TOKEN"}, exp: "env-token"},
+		{name: "env unset", g: &Github{TokenEnv: "OKRS_NO_TOKEN"}, err: true},
+		{name: "file", g: &Github{TokenFile: tokenFile}, exp: "file-token"},
+		{name: "file missing", g: &Github{TokenFile: filepath.Join(dir, "missing")}, err: true},
+		{
+			name: "gh", g: &Github{},
+			hosts: "github.com:\n    user: alice\n    oauth_token: gh-token\n",
+			exp:   "gh-token",
+		},
+		{
+			name: "gh enterprise", g: &Github{BaseURL: "https://ghe.example.com/api/v3"},
+			hosts: "github.com:\n    oauth_token: gh-token\nghe.example.com:\n    oauth_token: ghe-token\n",
+			exp:   "ghe-token",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(c.hosts), 0600))
+			ts, err := c.g.tokenSource()
+			if c.err {
+				require.Error(t, err)
+				// must not fall back to an unauthenticated client
+				_, err = c.g.client(context.Background())
+				require.Error(t, err)
+				require.Nil(t, c.g.hcli)
+				return
+			}
+			require.NoError(t, err)
+			if c.exp == "" {
+				require.Nil(t, ts)
+				return
+			}
+			require.NotNil(t, ts)
+			tok, err := ts.Token()
+			require.NoError(t, err)
+			require.Equal(t, c.exp, tok.AccessToken)
+		})
+	}
+}
+
+func TestAppToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	require.NoError(t, ioutil.WriteFile(keyFile, data, 0600))
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			http.Error(w, "expected JWT", http.StatusUnauthorized)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var claims struct {
+			Iss int64 `json:"iss"`
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if err := json.Unmarshal(payload, &claims); err != nil || claims.Iss != 7 {
+			http.Error(w, "unexpected issuer", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token": "installation-token", "expires_at": time.Now().Add(time.Hour),
+		})
+	}))
+	defer srv.Close()
+
+	g := &Github{
+		BaseURL: srv.URL + "/api/v3",
+		App:     &GHApp{ID: 7, InstallationID: 42, PrivateKeyFile: keyFile},
+	}
+	ts, err := g.tokenSource()
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		tok, err := ts.Token()
+		require.NoError(t, err)
+		require.Equal(t, "installation-token", tok.AccessToken)
+	}
+	require.Equal(t, 1, requests, "token must be reused until it expires")
+
+	g.App = &GHApp{ID: 7, InstallationID: 42}
+	_, err = g.tokenSource()
+	require.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+)
+
+// ghProjectsPreview is a media type required by the classic projects API.
+const ghProjectsPreview = "application/vnd.github.inertia-preview+json"
+
+// cache returns the file cache configured for Github responses.
+func (g *Github) cache() *fileCache {
+	ttl, _ := parseCacheTTL(g.CacheTTL)
+	return &fileCache{dir: g.Cache, prefix: "gh_", ttl: ttl, refresh: g.Refresh, offline: g.Offline}
+}
+
+// getCached requests a single API object and caches it.
+func (g *Github) getCached(ctx context.Context, key, path string, out interface{}) error {
+	c := g.cache()
+	if c.get(key, out) {
+		return nil
+	} else if err := c.checkOffline(key); err != nil {
+		return err
+	}
+	gh, err := g.client(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := gh.NewRequest("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	stale := c.stale(key)
+	if stale != nil {
+		stale.setHeaders(req)
+	}
+	resp, err := g.do(ctx, req, out)
+	if resp != nil && c.notModified(key, stale, resp.Response, out) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	c.put(key, out, resp.Response)
+	return nil
+}
+
+// listCached requests all pages of an API list and caches them as a single list.
+// Accept header is set to a given media type, if it's not empty.
+//
+// If the list is sorted by the update time, the expired entry is revalidated with a conditional request
+// for the first page only, since any change moves an item to that page. Other lists are always fetched
+// in full, as well as all lists in refresh mode.
+func (g *Github) listCached(ctx context.Context, key, path, accept string, sorted bool, out interface{}) error {
+	c := g.cache()
+	if c.get(key, out) {
+		return nil
+	} else if err := c.checkOffline(key); err != nil {
+		return err
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	gh, err := g.client(ctx)
+	if err != nil {
+		return err
+	}
+	getPage := func(page int, stale *cacheEntry) ([]json.RawMessage, *github.Response, error) {
+		req, err := gh.NewRequest("GET", fmt.Sprintf("%s%spage=%d&per_page=100", path, sep, page), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if stale != nil {
+			stale.setHeaders(req)
+		}
+		var buf []json.RawMessage
+		resp, err := g.do(ctx, req, &buf)
+		return buf, resp, err
+	}
+	var stale *cacheEntry
+	if sorted && !c.refresh {
+		stale = c.stale(key)
+	}
+	all, first, err := getPage(1, stale)
+	if first != nil && c.notModified(key, stale, first.Response, out) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if last := first.LastPage; last > 1 {
+		// the number of pages is known from the Link header - fetch the rest concurrently
+		log.Printf("fetching %s: %d pages", key, last)
+		pages := make([][]json.RawMessage, last+1)
+		errs := make([]error, last+1)
+		var wg sync.WaitGroup
+		for page := 2; page <= last; page++ {
+			wg.Add(1)
+			go func(page int) {
+				defer wg.Done()
+				pages[page], _, errs[page] = getPage(page, nil)
+			}(page)
+		}
+		wg.Wait()
+		for page := 2; page <= last; page++ {
+			if errs[page] != nil {
+				return errs[page]
+			}
+			all = append(all, pages[page]...)
+		}
+	} else if len(all) != 0 && (first.NextPage != 0 || first.Header.Get("Link") == "") {
+		for page := 2; ; page++ {
+			buf, resp, err := getPage(page, nil)
+			if err != nil {
+				return err
+			}
+			all = append(all, buf...)
+			if len(buf) == 0 || (resp.NextPage == 0 && resp.Header.Get("Link") != "") {
+				break
+			}
+		}
+	}
+	if all == nil {
+		all = []json.RawMessage{}
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return err
+	}
+	c.put(key, json.RawMessage(data), first.Response)
+	return nil
+}
@@ -0,0 +1,114 @@
+package okrs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	routes := fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+		},
+	}
+	var requests, conditional int
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		routes.ServeHTTP(w, r)
+	})
+	load := func(conf func(g *Github)) (*Node, error) {
+		g := newFakeGithub(t, h)
+		g.Cache = dir
+		g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+		conf(g)
+		tr := NewTree()
+		err := g.LoadTree(ctx, tr)
+		return tr.Root(), err
+	}
+
+	// legacy cache files are ignored
+	legacy := filepath.Join(dir, "gh_"+issuesKey("org", "repo")+".json")
+	require.NoError(t, ioutil.WriteFile(legacy, []byte(`[{"number":1}]`), 0644))
+
+	_, err := load(func(g *Github) { g.Offline, g.Refresh = true, true })
+	require.Error(t, err)
+	_, err = load(func(g *Github) { g.Offline = true })
+	require.Error(t, err)
+	require.Equal(t, 0, requests)
+
+	root, err := load(func(g *Github) { g.CacheTTL = "1h" })
+	require.NoError(t, err)
+	require.Equal(t, "Objective", root.Sub[0].Title)
+	require.Equal(t, 2, requests) // two pages
+
+	// fresh entries are not requested again
+	root, err = load(func(g *Github) { g.CacheTTL = "1h" })
+	require.NoError(t, err)
+	require.Equal(t, "Objective", root.Sub[0].Title)
+	require.Equal(t, 2, requests)
+
+	// refresh fetches all pages again
+	root, err = load(func(g *Github) { g.Refresh = true })
+	require.NoError(t, err)
+	require.Equal(t, "Objective", root.Sub[0].Title)
+	require.Equal(t, 4, requests)
+	require.Equal(t, 0, conditional)
+
+	// stale entries are revalidated
+	root, err = load(func(g *Github) { g.CacheTTL = "1ns" })
+	require.NoError(t, err)
+	require.Equal(t, "Objective", root.Sub[0].Title)
+	require.Equal(t, 5, requests)
+	require.Equal(t, 1, conditional)
+
+	// offline mode uses entries of any age
+	root, err = load(func(g *Github) { g.CacheTTL, g.Offline = "1ns", true })
+	require.NoError(t, err)
+	require.Equal(t, "Objective", root.Sub[0].Title)
+	require.Equal(t, 5, requests)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "temporary files must be removed")
+}
+
+func TestCacheUnsortedList(t *testing.T) {
+	ctx := context.Background()
+	routes := fakeRoutes{
+		"/orgs/org/projects": []*github.Project{
+			{ID: github.Int64(7), Name: github.String("OKRs"), Number: github.Int(1)},
+		},
+	}
+	var conditional int
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		routes.ServeHTTP(w, r)
+	})
+	g := newFakeGithub(t, h)
+	g.Cache, g.CacheTTL = t.TempDir(), "1ns"
+	for i := 0; i < 2; i++ {
+		list, err := g.listProjects(ctx, "org")
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+	}
+	// changes may be on any page of lists that are not sorted by the update time
+	require.Equal(t, 0, conditional)
+}
@@ -0,0 +1,467 @@
+package okrs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// GHProjectV2 selects a project (v2) of the organization by its number.
+type GHProjectV2 struct {
+	Number int `json:"number" yaml:"number"`
+	// Fields maps custom fields of the project to fields of the nodes.
+	Fields GHProjectFields `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// DoneStatus is a list of status values that mark items as completed. Defaults to "Done".
+	DoneStatus []string `json:"done_status,omitempty" yaml:"done_status,omitempty"`
+}
+
+// GHProjectFields contains names of custom project fields that are mapped to node fields.
+// Empty names are replaced with defaults listed in comments.
+type GHProjectFields struct {
+	Status   string `json:"status,omitempty" yaml:"status,omitempty"`     // Status
+	Priority string `json:"priority,omitempty" yaml:"priority,omitempty"` // Priority
+	Period   string `json:"period,omitempty" yaml:"period,omitempty"`     // Quarter
+	Progress string `json:"progress,omitempty" yaml:"progress,omitempty"` // Progress
+	Target   string `json:"target,omitempty" yaml:"target,omitempty"`     // Target
+	Current  string `json:"current,omitempty" yaml:"current,omitempty"`   // Current
+	Team     string `json:"team,omitempty" yaml:"team,omitempty"`         // Team
+	Due      string `json:"due,omitempty" yaml:"due,omitempty"`           // Due
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// graphQL executes GraphQL queries. It's an interface to allow replacing it in tests.
+type graphQL interface {
+	Query(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error
+}
+
+// ghGraphQL is a minimal client for Github GraphQL API.
+type ghGraphQL struct {
+	url string
+	// do sends the request. Unsuccessful responses are returned as errors.
+	do func(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+func (c *ghGraphQL) Query(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"query": query, "variables": vars,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return fmt.Errorf("graphql request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var r struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+	if len(r.Errors) != 0 {
+		return fmt.Errorf("graphql error: %s", r.Errors[0].Message)
+	}
+	return json.Unmarshal(r.Data, out)
+}
+
+func (g *Github) graphQL(ctx context.Context) (graphQL, error) {
+	if g.gql == nil {
+		hcli, err := g.httpClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		g.gql = &ghGraphQL{
+			url: g.graphQLURL(),
+			do: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				return g.doHTTP(ctx, hcli, req)
+			},
+		}
+	}
+	return g.gql, nil
+}
+
+const gqlProjectV2Items = `query($org: String!, $number: Int!, $cursor: String) {
+  organization(login: $org) {
+    projectV2(number: $number) {
+      title
+      url
+      items(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id
+          type
+          content {
+            ... on DraftIssue { title body }
+            ... on Issue {
+              url number title body state stateReason
+              repository { nameWithOwner }
+              assignees(first: 20) { nodes { login } }
+            }
+            ... on PullRequest {
+              url number title body state
+              repository { nameWithOwner }
+              assignees(first: 20) { nodes { login } }
+            }
+          }
+          fieldValues(first: 50) {
+            nodes {
+              ... on ProjectV2ItemFieldTextValue { text field { ... on ProjectV2FieldCommon { name } } }
+              ... on ProjectV2ItemFieldNumberValue { number field { ... on ProjectV2FieldCommon { name } } }
+              ... on ProjectV2ItemFieldSingleSelectValue { name field { ... on ProjectV2FieldCommon { name } } }
+              ... on ProjectV2ItemFieldIterationValue { title field { ... on ProjectV2FieldCommon { name } } }
+              ... on ProjectV2ItemFieldDateValue { date field { ... on ProjectV2FieldCommon { name } } }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type gqlProjectV2 struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Items struct {
+		PageInfo struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+		Nodes []gqlProjectItem `json:"nodes"`
+	} `json:"items"`
+}
+
+type gqlProjectItem struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Content struct {
+		URL         string `json:"url"`
+		Number      int    `json:"number"`
+		Title       string `json:"title"`
+		Body        string `json:"body"`
+		State       string `json:"state"`
+		StateReason string `json:"stateReason"`
+		Repository  struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"repository"`
+		Assignees struct {
+			Nodes []struct {
+				Login string `json:"login"`
+			} `json:"nodes"`
+		} `json:"assignees"`
+	} `json:"content"`
+	FieldValues struct {
+		Nodes []gqlFieldValue `json:"nodes"`
+	} `json:"fieldValues"`
+}
+
+// gqlFieldValue is a value of a custom project field. Only one of the values is set, depending on the field type.
+type gqlFieldValue struct {
+	Field struct {
+		Name string `json:"name"`
+	} `json:"field"`
+	Text   *string  `json:"text"`
+	Number *float64 `json:"number"`
+	Name   *string  `json:"name"`  // single select
+	Title  *string  `json:"title"` // iteration
+	Date   *string  `json:"date"`
+}
+
+func (v gqlFieldValue) value() string {
+	switch {
+	case v.Text != nil:
+		return strings.TrimSpace(*v.Text)
+	case v.Number != nil:
+		return strconv.FormatFloat(*v.Number, 'f', -1, 64)
+	case v.Name != nil:
+		return strings.TrimSpace(*v.Name)
+	case v.Title != nil:
+		return strings.TrimSpace(*v.Title)
+	case v.Date != nil:
+		return *v.Date
+	}
+	return ""
+}
+
+type ghProjectV2 struct {
+	org   *ghOrg
+	conf  GHProjectV2
+	title string
+	url   string
+	items []gqlProjectItem
+}
+
+// load fetches all items of the project.
+func (p *ghProjectV2) load(ctx context.Context) error {
+	g := p.org.g
+	c := g.cache()
+	key := fmt.Sprintf("%s_project_v2_%d", p.org.name, p.conf.Number)
+	var cached struct {
+		Title string           `json:"title"`
+		URL   string           `json:"url"`
+		Items []gqlProjectItem `json:"items"`
+	}
+	if c.get(key, &cached) {
+		p.title, p.url, p.items = cached.Title, cached.URL, cached.Items
+		return nil
+	}
+	if err := c.checkOffline(key); err != nil {
+		return err
+	}
+	gql, err := g.graphQL(ctx)
+	if err != nil {
+		return err
+	}
+	vars := map[string]interface{}{
+		"org": p.org.name, "number": p.conf.Number,
+	}
+	for {
+		var resp struct {
+			Organization struct {
+				ProjectV2 *gqlProjectV2 `json:"projectV2"`
+			} `json:"organization"`
+		}
+		if err := gql.Query(ctx, gqlProjectV2Items, vars, &resp); err != nil {
+			return err
+		}
+		proj := resp.Organization.ProjectV2
+		if proj == nil {
+			return fmt.Errorf("cannot find project %d in %s", p.conf.Number, p.org.name)
+		}
+		p.title, p.url = proj.Title, proj.URL
+		p.items = append(p.items, proj.Items.Nodes...)
+		if !proj.Items.PageInfo.HasNextPage {
+			break
+		}
+		vars["cursor"] = proj.Items.PageInfo.EndCursor
+	}
+	cached.Title, cached.URL, cached.Items = p.title, p.url, p.items
+	c.put(key, cached, nil)
+	return nil
+}
+
+// itemNode returns a node for the project item and a reference to its parent from the issue body.
+func (p *ghProjectV2) itemNode(ctx context.Context, tr *Tree, it gqlProjectItem) (string, *Node, *Link, error) {
+	c := it.Content
+	switch it.Type {
+	case "DRAFT_ISSUE":
+		nd := tr.NewNode(Node{ID: it.ID})
+		nd.Title, nd.Priority = issueTitle(c.Title)
+		local := NewTree()
+		if err := ParseMDTree(strings.NewReader(c.Body), local); err != nil {
+			return "", nil, nil, err
+		}
+		if l := local.Root(); l != nil {
+			mergeLocal(nd, l)
+			return it.ID, nd, l.parent, nil
+		}
+		return it.ID, nd, nil, nil
+	case "ISSUE", "PULL_REQUEST":
+	default:
+		return "", nil, nil, nil // redacted
+	}
+	i := strings.Index(c.Repository.NameWithOwner, "/")
+	if i < 0 {
+		return "", nil, nil, fmt.Errorf("unexpected repository name: %q", c.Repository.NameWithOwner)
+	}
+	org, repo := c.Repository.NameWithOwner[:i], c.Repository.NameWithOwner[i+1:]
+	key := fmt.Sprintf("%s/%s#%d", org, repo, c.Number)
+
+	is := p.org.g.loadedIssue(org, repo, c.Number)
+	if is == nil || is.node == nil {
+		state := strings.ToLower(c.State)
+		if state == "merged" {
+			state = "closed"
+		}
+		iss := &github.Issue{
+			Number:  github.Int(c.Number),
+			Title:   github.String(c.Title),
+			Body:    github.String(c.Body),
+			State:   github.String(state),
+			HTMLURL: github.String(c.URL),
+			URL:     github.String(fmt.Sprintf("%srepos/%s/%s/issues/%d", p.org.g.apiURL(), org, repo, c.Number)),
+		}
+		for _, u := range c.Assignees.Nodes {
+			iss.Assignees = append(iss.Assignees, &github.User{Login: github.String(u.Login)})
+		}
+		var err error
+		is, err = p.org.g.standaloneIssue(ctx, tr, org, repo, iss, strings.ToLower(c.StateReason))
+		if err != nil {
+			return "", nil, nil, err
+		}
+	}
+	var par *Link
+	if l := is.local.Root(); l != nil && l.parent != nil {
+		// normalize the reference, so it can be matched with other items
+		pl := *l.parent
+		if porg, prepo, num, ok := p.org.g.parseIssueURL(pl.URL); ok {
+			pl.URL = fmt.Sprintf("%s/%s#%d", porg, prepo, num)
+		} else if sub := reHashRef.FindStringSubmatch(pl.Title); len(sub) != 0 {
+			pl.URL = fmt.Sprintf("%s/%s#%s", org, repo, sub[1])
+		}
+		par = &pl
+	}
+	return key, is.node, par, nil
+}
+
+var reFieldPriority = regexp.MustCompile(`(?i)^\W*P?(\d+)\b`)
+
+// parseFieldProgress parses a value of the progress field. Values with a "%" suffix and values above 1
+// are percents, while other values are fractions, as used by number fields.
+func parseFieldProgress(val string) (*Progress, bool) {
+	perc := strings.HasSuffix(val, "%")
+	v, _, err := parseValue(strings.TrimSuffix(val, "%"))
+	if err != nil || v < 0 {
+		return nil, false
+	}
+	if !perc && v <= 1 {
+		v *= 100
+	}
+	return &Progress{Done: int(math.Round(v)), Total: 100}, true
+}
+
+// applyFields sets node fields from custom fields of the project item.
+func (p *ghProjectV2) applyFields(nd *Node, it gqlProjectItem) {
+	f := p.conf.Fields
+	done := false
+	for _, v := range it.FieldValues.Nodes {
+		name, val := v.Field.Name, v.value()
+		if name == "" || val == "" {
+			continue
+		}
+		switch {
+		case strings.EqualFold(name, orDefault(f.Status, "Status")):
+			done = matchAny(p.conf.DoneStatus, "Done", val)
+		case strings.EqualFold(name, orDefault(f.Priority, "Priority")):
+			if sub := reFieldPriority.FindStringSubmatch(val); len(sub) != 0 {
+				if pr, err := strconv.Atoi(sub[1]); err == nil {
+					nd.Priority = &pr
+				}
+			}
+		case strings.EqualFold(name, orDefault(f.Period, "Quarter")):
+			if per := parsePeriod(val); per != "" {
+				nd.Period = per
+			} else {
+				nd.Period = val
+			}
+		case strings.EqualFold(name, orDefault(f.Progress, "Progress")):
+			if pr, ok := parseFieldProgress(val); ok {
+				nd.Progress = pr
+			}
+		case strings.EqualFold(name, orDefault(f.Target, "Target")):
+			if v, unit, err := parseValue(val); err == nil {
+				if nd.Metric == nil {
+					nd.Metric = &Metric{}
+				}
+				nd.Metric.Target = v
+				if unit != "" {
+					nd.Metric.Unit = unit
+				}
+			}
+		case strings.EqualFold(name, orDefault(f.Current, "Current")):
+			if v, unit, err := parseValue(val); err == nil {
+				if nd.Metric == nil {
+					nd.Metric = &Metric{}
+				}
+				nd.Metric.Current = &v
+				if unit != "" {
+					nd.Metric.Unit = unit
+				}
+			}
+		case strings.EqualFold(name, orDefault(f.Team, "Team")):
+			nd.Team = val
+		case strings.EqualFold(name, orDefault(f.Due, "Due")):
+			if t, err := parseDate(val); err == nil {
+				nd.Due = t
+			}
+		}
+	}
+	if done {
+		// status takes precedence over the progress field
+		markDone(nd)
+	}
+}
+
+func (p *ghProjectV2) asTree(ctx context.Context, tr *Tree) (*Node, error) {
+	root := tr.NewNode(Node{
+		Title: p.title,
+		Link: Link{
+			Title: p.title, URL: p.url,
+		},
+		Period: parsePeriod(p.title),
+	})
+	type item struct {
+		node   *Node
+		parent *Link
+	}
+	var items []item
+	byKey := make(map[string]*Node)
+	for _, it := range p.items {
+		key, nd, par, err := p.itemNode(ctx, tr, it)
+		if err != nil {
+			return root, err
+		} else if nd == nil {
+			continue
+		}
+		p.applyFields(nd, it)
+		byKey[key] = nd
+		items = append(items, item{node: nd, parent: par})
+	}
+	// nest items under their parents, if parents are in the same project
+	for _, it := range items {
+		var par *Node
+		if it.parent != nil {
+			par = byKey[it.parent.URL]
+		}
+		if par == nil {
+			if err := root.AddChild(it.node); err != nil {
+				return root, fmt.Errorf("invalid item in project %q: %v", p.title, err)
+			}
+			continue
+		}
+		if containsNode(par.Sub, it.node) {
+			continue // already linked in the repository tree
+		}
+		if err := par.AddChild(it.node); err != nil {
+			err = fmt.Errorf("invalid parent of an item in project %q: %v", p.title, err)
+			if err = tr.loadError(RuleCycle, it.node, err); err != nil {
+				return root, err
+			}
+		}
+	}
+	for _, it := range items {
+		markOpen(it.node)
+	}
+	return root, nil
+}
+
+func containsNode(arr []*Node, n *Node) bool {
+	for _, s := range arr {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
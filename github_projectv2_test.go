@@ -0,0 +1,97 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordedGraphQL replies with recorded responses from testdata, selected by the page cursor.
+type recordedGraphQL map[string]string
+
+func (r recordedGraphQL) Query(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	cursor, _ := vars["cursor"].(string)
+	name, ok := r[cursor]
+	if !ok {
+		return fmt.Errorf("unexpected cursor: %q", cursor)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+func TestProjectsV2(t *testing.T) {
+	g := &Github{gql: recordedGraphQL{
+		"":             "github_projectv2_page1.json",
+		"Y3Vyc29yOjI=": "github_projectv2_page2.json",
+	}}
+	g.Orgs = []GHOrg{{Name: "org", ProjectsV2: []GHProjectV2{{
+		Number:     5,
+		Fields:     GHProjectFields{Status: "State"},
+		DoneStatus: []string{"Shipped"},
+	}}}}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	root := tr.Root()
+	require.Equal(t, "Company OKRs", root.Title)
+	require.Equal(t, "https://github.com/orgs/org/projects/5", root.Link.URL)
+	require.Len(t, root.Sub, 2)
+
+	obj := root.Sub[0]
+	require.Equal(t, "Grow revenue", obj.Title)
+	require.Equal(t, "Revenue is important.", obj.Desc)
+	require.Equal(t, pri(0), obj.Priority)
+	require.Equal(t, "2026-Q4", obj.Period)
+	require.Equal(t, []string{"alice"}, obj.Owners)
+	require.Nil(t, obj.Progress, "status field is remapped")
+
+	require.Len(t, obj.Sub, 1)
+	kr := obj.Sub[0]
+	require.Equal(t, "Close 10 deals", kr.Title)
+	require.Equal(t, Link{Title: "#7", URL: "https://github.com/org/sales/issues/7"}, kr.Link)
+	require.Equal(t, pri(1), kr.Priority)
+	require.Equal(t, &Metric{Target: 10, Current: val(4)}, kr.Metric)
+	require.Equal(t, date("2026-12-15"), kr.Due)
+
+	draft := root.Sub[1]
+	require.Equal(t, "Hire sales team", draft.Title)
+	require.Equal(t, "sales", draft.Team)
+	require.Equal(t, &Progress{Done: 100, Total: 100}, draft.Progress)
+}
+
+func TestParseFieldProgress(t *testing.T) {
+	cases := []struct {
+		val string
+		exp *Progress
+	}{
+		{val: "40", exp: &Progress{Done: 40, Total: 100}},
+		{val: "66.7", exp: &Progress{Done: 67, Total: 100}},
+		{val: "12.5%", exp: &Progress{Done: 13, Total: 100}},
+		{val: "0.25", exp: &Progress{Done: 25, Total: 100}},
+		{val: "1", exp: &Progress{Done: 100, Total: 100}},
+		{val: "1%", exp: &Progress{Done: 1, Total: 100}},
+		{val: "abc"},
+	}
+	for _, c := range cases {
+		t.Run(c.val, func(t *testing.T) {
+			pr, ok := parseFieldProgress(c.val)
+			require.Equal(t, c.exp != nil, ok)
+			require.Equal(t, c.exp, pr)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package okrs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// issueBody renders the body of an issue created for the node.
+// Sub-nodes are not included, since they are linked to the issue with a parent reference instead.
+func issueBody(n *Node, parent string) string {
+	var lines []string
+	if parent != "" {
+		lines = append(lines, "**Parent:** "+parent)
+	}
+	if len(n.Owners) != 0 {
+		lines = append(lines, "**Owner:** "+formatOwners(n.Owners))
+	}
+	if p := n.Progress; p != nil {
+		if p.Total == 100 {
+			lines = append(lines, fmt.Sprintf("**Progress:** %d%%", p.Done))
+		} else {
+			lines = append(lines, fmt.Sprintf("**Progress:** %d/%d", p.Done, p.Total))
+		}
+	}
+	if n.Period != "" && n.Period == parsePeriod(n.Title) {
+		// mdFields assumes the period is parsed from the heading, but issue titles are not parsed
+		lines = append(lines, "**Period:** "+n.Period)
+	}
+	lines = append(lines, mdFields(n)...)
+	if n.Desc != "" {
+		lines = append(lines, n.Desc)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n\n") + "\n"
+}
+
+// hasParentRef checks if the issue body contains a parent reference.
+func hasParentRef(body string) bool {
+	body, _ = splitSyncBlock(body)
+	tr := NewTree()
+	if err := ParseMDTree(strings.NewReader(body), tr); err != nil {
+		return false
+	}
+	root := tr.Root()
+	return root != nil && root.parent != nil
+}
+
+// issueTitleFor returns a title of the issue created for the node.
+func issueTitleFor(n *Node) string {
+	if n.Priority != nil {
+		return fmt.Sprintf("[P%d] %s", *n.Priority, n.Title)
+	}
+	return n.Title
+}
+
+// Publish creates an issue in the repository for each objective and key result of the tree.
+// Sub-nodes are linked to issues of their parents with a "**Parent:** #N" line in the body.
+//
+// Nodes that already have an issue with the same ID, URL or title are not created again,
+// thus it's safe to run it multiple times for the same document. Existing issues without
+// a parent reference are linked to the parent from the document.
+// If dryRun is set, planned issues are written to w and the repository is not modified.
+func (g *Github) Publish(ctx context.Context, w io.Writer, org, repo string, tr *Tree, dryRun bool) error {
+	if err := g.setup(ctx); err != nil {
+		return err
+	} else if g.Offline && !dryRun {
+		return fmt.Errorf("cannot create issues in offline mode")
+	}
+	issues, err := g.listIssues(ctx, org, repo)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*github.Issue)
+	for _, iss := range issues {
+		is := iss.Issue
+		title, _ := issueTitle(is.GetTitle())
+		for _, k := range []string{
+			is.GetURL(), is.GetHTMLURL(),
+			fmt.Sprintf("#%d", is.GetNumber()),
+			title,
+		} {
+			if _, ok := existing[k]; !ok && k != "" {
+				existing[k] = is
+			}
+		}
+	}
+	find := func(n *Node) *github.Issue {
+		title, _ := issueTitle(n.Title)
+		for _, k := range []string{n.ID, n.Link.URL, n.Link.Title, title} {
+			if k == "" {
+				continue
+			}
+			if is, ok := existing[k]; ok {
+				return is
+			}
+		}
+		return nil
+	}
+
+	modified := false
+	var publish func(n *Node, parent string) error
+	publish = func(n *Node, parent string) error {
+		ref := parent
+		if !n.isProxyNode() {
+			if is := find(n); is != nil {
+				ref = fmt.Sprintf("#%d", is.GetNumber())
+				if parent == "" || hasParentRef(is.GetBody()) {
+					log.Printf("%s/%s%s already exists: %s", org, repo, ref, is.GetTitle())
+				} else if dryRun {
+					if _, err = fmt.Fprintf(w, "link %s under %s\n", ref, parent); err != nil {
+						return err
+					}
+				} else {
+					// link an existing issue to the parent
+					body := "**Parent:** " + parent + "\n\n" + strings.TrimLeft(is.GetBody(), "\r\n")
+					_, err := g.editIssue(ctx, org, repo, is.GetNumber(), &github.IssueRequest{
+						Body: &body,
+					})
+					if err != nil {
+						return err
+					}
+					modified = true
+					log.Printf("linked %s/%s%s to %s", org, repo, ref, parent)
+				}
+			} else if n.Title == "" {
+				return fmt.Errorf("cannot create an issue for %s: no title", nodeName(n))
+			} else {
+				title, body := issueTitleFor(n), issueBody(n, parent)
+				if dryRun {
+					ref = fmt.Sprintf("%q", title)
+					if parent == "" {
+						_, err = fmt.Fprintf(w, "create %s\n", ref)
+					} else {
+						_, err = fmt.Fprintf(w, "create %s under %s\n", ref, parent)
+					}
+					if err != nil {
+						return err
+					}
+				} else {
+					is, err := g.createIssue(ctx, org, repo, &github.IssueRequest{
+						Title: &title, Body: &body,
+					})
+					if err != nil {
+						return err
+					}
+					modified = true
+					ref = fmt.Sprintf("#%d", is.GetNumber())
+					log.Printf("created %s/%s%s: %s", org, repo, ref, title)
+				}
+			}
+		}
+		for _, s := range n.Sub {
+			if err := publish(s, ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	root := acyclic(tr.Root())
+	if root == nil {
+		return nil
+	}
+	err = publish(root, "")
+	if modified {
+		// cached issue list is stale now
+		if err2 := g.cache().drop(issuesKey(org, repo)); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// createIssue creates an issue in the repository.
+func (g *Github) createIssue(ctx context.Context, org, repo string, is *github.IssueRequest) (*github.Issue, error) {
+	return g.sendIssue(ctx, "POST", fmt.Sprintf("repos/%s/%s/issues", org, repo), is)
+}
+
+// editIssue updates fields of the issue that are set in the request.
+func (g *Github) editIssue(ctx context.Context, org, repo string, num int, is *github.IssueRequest) (*github.Issue, error) {
+	return g.sendIssue(ctx, "PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", org, repo, num), is)
+}
+
+// sendIssue sends an issue request with the same limits and retries as other API requests.
+func (g *Github) sendIssue(ctx context.Context, method, path string, is *github.IssueRequest) (*github.Issue, error) {
+	gh, err := g.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := gh.NewRequest(method, path, is)
+	if err != nil {
+		return nil, err
+	}
+	var out github.Issue
+	if _, err = g.do(ctx, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
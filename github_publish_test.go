@@ -0,0 +1,194 @@
+package okrs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+const publishDoc = `# [P0] Objective A
+
+**Owner:** @alice
+
+Make things better.
+
+* [P1] KR one
+* KR two
+  **Due:** 2026-12-31
+
+# Objective B
+
+* KR three
+`
+
+// fakeIssues is a minimal fake of Github issues API for a single repository.
+type fakeIssues struct {
+	issues []*github.Issue
+}
+
+func (f *fakeIssues) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const path = "/repos/org/repo/issues"
+	if strings.HasPrefix(r.URL.Path, path+"/") && r.Method == http.MethodPatch {
+		var req github.IssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		num, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, path+"/"))
+		if err != nil || num < 1 || num > len(f.issues) {
+			http.NotFound(w, r)
+			return
+		}
+		is := f.issues[num-1]
+		if req.Body != nil {
+			is.Body = req.Body
+		}
+		json.NewEncoder(w).Encode(is)
+		return
+	} else if r.URL.Path != path {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var out []*github.Issue
+		if r.URL.Query().Get("page") == "1" {
+			out = f.issues
+		}
+		json.NewEncoder(w).Encode(out)
+	case http.MethodPost:
+		var req github.IssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		num := len(f.issues) + 1
+		is := &github.Issue{
+			Number:  github.Int(num),
+			Title:   req.Title,
+			Body:    req.Body,
+			URL:     github.String(fmt.Sprintf("https://api.github.com%s/%d", path, num)),
+			HTMLURL: github.String(fmt.Sprintf("https://github.com/org/repo/issues/%d", num)),
+		}
+		f.issues = append(f.issues, is)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(is)
+	default:
+		http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeGithub(t testing.TB, h http.Handler) *Github {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	cli := github.NewClient(srv.Client())
+	u, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	cli.BaseURL = u
+	return &Github{cli: cli}
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeIssues{issues: []*github.Issue{{
+		Number: github.Int(1), Title: github.String("[P1] KR one"),
+		URL:     github.String("https://api.github.com/repos/org/repo/issues/1"),
+		HTMLURL: github.String("https://github.com/org/repo/issues/1"),
+	}}}
+	g := newFakeGithub(t, fake)
+
+	doc := NewTree()
+	require.NoError(t, ParseMDTree(strings.NewReader(publishDoc), doc))
+
+	buf := bytes.NewBuffer(nil)
+	err := g.Publish(ctx, buf, "org", "repo", doc, true)
+	require.NoError(t, err)
+	require.Equal(t, `create "[P0] Objective A"
+link #1 under "[P0] Objective A"
+create "KR two" under "[P0] Objective A"
+create "Objective B"
+create "KR three" under "Objective B"
+`, buf.String())
+	require.Len(t, fake.issues, 1)
+
+	err = g.Publish(ctx, buf, "org", "repo", doc, false)
+	require.NoError(t, err)
+	require.Len(t, fake.issues, 5)
+
+	titles := make(map[string]string)
+	for _, is := range fake.issues {
+		titles[is.GetTitle()] = is.GetBody()
+	}
+	require.Equal(t, "**Owner:** @alice\n\nMake things better.\n", titles["[P0] Objective A"])
+	require.Equal(t, "**Parent:** #2\n\n**Due:** 2026-12-31\n", titles["KR two"])
+	require.Equal(t, "**Parent:** #4\n", titles["KR three"])
+	require.Equal(t, "**Parent:** #2\n\n", titles["[P1] KR one"])
+
+	// publishing again must not create duplicates
+	err = g.Publish(ctx, buf, "org", "repo", doc, false)
+	require.NoError(t, err)
+	require.Len(t, fake.issues, 5)
+
+	// issues must be loaded back as the same tree
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	tr := NewTree()
+	require.NoError(t, g.LoadTree(ctx, tr))
+	var find func(n *Node, title string) *Node
+	find = func(n *Node, title string) *Node {
+		if n.Title == title {
+			return n
+		}
+		for _, s := range n.Sub {
+			if f := find(s, title); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+	titlesOf := func(n *Node) []string {
+		var out []string
+		for _, s := range n.Sub {
+			out = append(out, s.Title)
+		}
+		return out
+	}
+	repoNode := find(tr.Root(), "repo")
+	require.NotNil(t, repoNode)
+	repoNode.Sort()
+	require.Equal(t, []string{"Objective A", "Objective B"}, titlesOf(repoNode))
+	objA := repoNode.Sub[0]
+	require.Equal(t, pri(0), objA.Priority)
+	require.Equal(t, []string{"alice"}, objA.Owners)
+	objA.Sort()
+	require.Equal(t, []string{"KR one", "KR two"}, titlesOf(objA))
+}
+
+func TestIssueTitle(t *testing.T) {
+	cases := []struct {
+		title string
+		exp   string
+		pri   *int
+	}{
+		{title: "[P1] KR one", exp: "KR one", pri: pri(1)},
+		{title: "  [P0]Objective", exp: "Objective", pri: pri(0)},
+		{title: "Fix [P1] later", exp: "Fix [P1] later"},
+		{title: "No priority", exp: "No priority"},
+	}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			title, pr := issueTitle(c.title)
+			require.Equal(t, c.exp, title)
+			require.Equal(t, c.pri, pr)
+		})
+	}
+}
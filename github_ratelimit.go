@@ -0,0 +1,206 @@
+package okrs
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	// ghConcurrency is the default number of concurrent API requests.
+	ghConcurrency = 4
+	// ghMaxRetries is the maximal number of retries for requests that hit rate limits.
+	ghMaxRetries = 5
+	// ghMaxBackoff limits the delay between retries when the server doesn't specify it.
+	ghMaxBackoff = time.Minute
+)
+
+// ghRetryBackoff is the initial delay between retries when the server doesn't specify it.
+var ghRetryBackoff = time.Second
+
+// ghLimiter limits the number of concurrent requests and pauses them when the rate limit is exhausted.
+type ghLimiter struct {
+	mu    sync.Mutex
+	sem   chan struct{}
+	reset time.Time // requests are paused until this time
+}
+
+func (l *ghLimiter) acquire(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.sem == nil {
+		l.sem = make(chan struct{}, n)
+	}
+	sem := l.sem
+	l.mu.Unlock()
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *ghLimiter) release() {
+	<-l.sem
+}
+
+// update records the rate limit reported in the response.
+func (l *ghLimiter) update(r github.Rate) {
+	if r.Limit == 0 || r.Remaining != 0 {
+		return
+	}
+	l.mu.Lock()
+	if r.Reset.Time.After(l.reset) {
+		l.reset = r.Reset.Time
+	}
+	l.mu.Unlock()
+}
+
+// wait blocks until the rate limit is reset.
+func (l *ghLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	d := time.Until(l.reset)
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	log.Printf("rate limit exhausted, waiting %v", d.Round(time.Second))
+	return sleepCtx(ctx, d)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *Github) concurrency() int {
+	if g.Concurrency <= 0 {
+		return ghConcurrency
+	}
+	return g.Concurrency
+}
+
+// do sends an API request. The number of concurrent requests is limited, and requests that hit
+// primary or secondary rate limits are retried after the delay requested by the server.
+func (g *Github) do(ctx context.Context, req *http.Request, out interface{}) (*github.Response, error) {
+	gh, err := g.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return g.limit(ctx, req, func() (*github.Response, error) {
+		return gh.Do(ctx, req, out)
+	})
+}
+
+// doHTTP sends a request that the github package cannot build, like GraphQL queries, with the same
+// limits as do. Unsuccessful responses are returned as errors, and their body is already closed.
+func (g *Github) doHTTP(ctx context.Context, hcli *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := g.limit(ctx, req, func() (*github.Response, error) {
+		resp, err := hcli.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		r := &github.Response{Response: resp, Rate: parseRate(resp)}
+		if err = github.CheckResponse(resp); err != nil {
+			resp.Body.Close()
+		}
+		return r, err
+	})
+	if resp == nil {
+		return nil, err
+	}
+	return resp.Response, err
+}
+
+// parseRate reads the rate limit from headers of the response.
+func parseRate(resp *http.Response) github.Rate {
+	var r github.Rate
+	r.Limit, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	r.Remaining, _ = strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if sec, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		r.Reset = github.Timestamp{Time: time.Unix(sec, 0)}
+	}
+	return r
+}
+
+// limit sends the request with send. The number of concurrent requests is limited, and requests
+// that hit rate limits are retried.
+func (g *Github) limit(ctx context.Context, req *http.Request, send func() (*github.Response, error)) (*github.Response, error) {
+	if err := g.lim.acquire(ctx, g.concurrency()); err != nil {
+		return nil, err
+	}
+	defer g.lim.release()
+	for attempt := 0; ; attempt++ {
+		if err := g.lim.wait(ctx); err != nil {
+			return nil, err
+		}
+		if attempt > 0 && req.GetBody != nil {
+			// the body was consumed by the previous attempt
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := send()
+		if resp != nil {
+			g.lim.update(resp.Rate)
+		}
+		d, ok := retryDelay(resp, err, attempt)
+		if !ok || attempt >= ghMaxRetries {
+			return resp, err
+		}
+		log.Printf("%s %s: %v; retrying in %v", req.Method, req.URL.Path, err, d.Round(time.Second))
+		if err := sleepCtx(ctx, d); err != nil {
+			return resp, err
+		}
+	}
+}
+
+// retryDelay checks if the request should be retried, and returns the delay before the next attempt.
+func retryDelay(resp *github.Response, err error, attempt int) (time.Duration, bool) {
+	switch e := err.(type) {
+	case nil:
+		return 0, false
+	case *github.RateLimitError:
+		d := time.Until(e.Rate.Reset.Time)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	case *github.AbuseRateLimitError:
+		if e.RetryAfter != nil {
+			return *e.RetryAfter, true
+		}
+		return backoff(attempt), true
+	}
+	if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return 0, false
+	}
+	// secondary rate limits are not always recognized by the github package
+	if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second, true
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		return backoff(attempt), true
+	}
+	return 0, false
+}
+
+func backoff(attempt int) time.Duration {
+	d := ghRetryBackoff << uint(attempt)
+	if d <= 0 || d > ghMaxBackoff {
+		d = ghMaxBackoff
+	}
+	return d
+}
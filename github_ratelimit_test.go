@@ -0,0 +1,163 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitRetry(t *testing.T) {
+	defer func(d time.Duration) { ghRetryBackoff = d }(ghRetryBackoff)
+	ghRetryBackoff = time.Millisecond
+
+	routes := fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+		},
+	}
+	var requests int
+	g := newFakeGithub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			// secondary rate limit
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			// primary rate limit, already reset
+			w.Header().Set("X-RateLimit-Limit", "60")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"API rate limit exceeded for 127.0.0.1."}`)
+		default:
+			routes.ServeHTTP(w, r)
+		}
+	}))
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+	require.Equal(t, "Objective", tr.Root().Sub[0].Title)
+	require.Equal(t, 5, requests) // 3 retries and 2 pages
+}
+
+func TestConcurrentLoading(t *testing.T) {
+	const pages = 3
+	var (
+		mu       sync.Mutex
+		inFlight int
+		maxSeen  int
+	)
+	g := newFakeGithub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(5 * time.Millisecond)
+
+		// /repos/org/NAME/issues
+		repo := strings.Split(r.URL.Path, "/")[3]
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		u := "http://" + r.Host + r.URL.Path
+		w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next", <%s?page=%d>; rel="last"`, u, page+1, u, pages))
+		if page == pages {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=1>; rel="first"`, u))
+		}
+		json.NewEncoder(w).Encode([]*github.Issue{
+			fakeIssue("org", repo, page, fmt.Sprintf("%s %d", repo, page), ""),
+		})
+	}))
+	g.Concurrency = 2
+	o := GHOrg{Name: "org"}
+	for i := 0; i < 5; i++ {
+		o.Repos = append(o.Repos, GHRepo{Name: fmt.Sprintf("repo%d", i)})
+	}
+	g.Orgs = []GHOrg{o}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	root := tr.Root()
+	require.Len(t, root.Sub, 5)
+	for _, r := range root.Sub {
+		require.Len(t, r.Sub, pages, r.Title)
+	}
+	require.True(t, maxSeen <= 2, "too many concurrent requests: %d", maxSeen)
+}
+
+func TestRateLimitRetryWrites(t *testing.T) {
+	fake := &fakeIssues{}
+	limited := false
+	g := newFakeGithub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && !limited {
+			limited = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	// the body must be sent again with the retried request
+	is, err := g.createIssue(context.Background(), "org", "repo", &github.IssueRequest{
+		Title: github.String("Objective"),
+	})
+	require.NoError(t, err)
+	require.True(t, limited)
+	require.Equal(t, 1, is.GetNumber())
+	require.Len(t, fake.issues, 1)
+	require.Equal(t, "Objective", fake.issues[0].GetTitle())
+}
+
+func TestRateLimitGraphQL(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/graphql", r.URL.Path)
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+			return
+		}
+		var req struct {
+			Query string `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "query { viewer }", req.Query)
+		fmt.Fprint(w, `{"data":{"viewer":"alice"}}`)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	g := &Github{BaseURL: srv.URL + "/api/v3", hcli: srv.Client()}
+	gql, err := g.graphQL(ctx)
+	require.NoError(t, err)
+	var out struct {
+		Viewer string `json:"viewer"`
+	}
+	require.NoError(t, gql.Query(ctx, "query { viewer }", nil, &out))
+	require.Equal(t, "alice", out.Viewer)
+	require.Equal(t, 2, requests)
+}
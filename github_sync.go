@@ -0,0 +1,178 @@
+package okrs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	syncBegin = "<!-- okrs:begin (generated by okrs, do not edit) -->"
+	syncEnd   = "<!-- okrs:end -->"
+)
+
+// splitSyncBlock splits the issue body into user-written content and a block generated by SyncProgress.
+func splitSyncBlock(body string) (user string, block string) {
+	i := strings.Index(body, syncBegin)
+	if i < 0 {
+		return body, ""
+	}
+	j := strings.Index(body[i:], syncEnd)
+	if j < 0 {
+		return body, ""
+	}
+	j += i + len(syncEnd)
+	user = strings.TrimRight(body[:i], "\r\n") + body[j:]
+	return user, body[i:j]
+}
+
+// replaceSyncBlock replaces the generated block in the issue body, or appends it if there is none.
+func replaceSyncBlock(body, block string) string {
+	i := strings.Index(body, syncBegin)
+	if i >= 0 {
+		if j := strings.Index(body[i:], syncEnd); j >= 0 {
+			j += i + len(syncEnd)
+			return body[:i] + block + body[j:]
+		}
+	}
+	body = strings.TrimRight(body, "\r\n")
+	if body == "" {
+		return block + "\n"
+	}
+	return body + "\n\n" + block + "\n"
+}
+
+// issueRef returns a reference to a sub-issue as it should be written in the body of the parent issue.
+func (r *ghRepo) issueRef(n *Node) string {
+	prefix := fmt.Sprintf("%s/%s/%s/issues/", r.org.g.webURL(), r.org.name, r.name)
+	if l := n.Link; strings.HasPrefix(l.URL, prefix) && reHashRef.MatchString(l.Title) {
+		return l.Title
+	} else if l.URL != "" {
+		return l.URL
+	}
+	return ""
+}
+
+// syncBlock renders the block with the progress of the issue and a checklist of its sub-issues.
+func (is *ghIssue) syncBlock() string {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(syncBegin + "\n")
+	if p := is.node.GetProgress(); p != (Progress{}) {
+		if p.Total == 100 {
+			fmt.Fprintf(buf, "**Progress:** %d%%\n", p.Done)
+		} else {
+			fmt.Fprintf(buf, "**Progress:** %d/%d\n", p.Done, p.Total)
+		}
+	}
+	if len(is.subs) != 0 {
+		subs := &Node{Sub: append([]*Node{}, is.subs...)}
+		subs.Sort()
+		buf.WriteString("\n")
+		for _, s := range subs.Sub {
+			check := " "
+			if p := s.GetProgress(); p.Total > 0 && p.IsDone() {
+				check = "x"
+			}
+			title := s.Title
+			if s.Status == StatusCanceled {
+				title = "~~" + title + "~~"
+			}
+			if s.Priority != nil {
+				title = fmt.Sprintf("[P%d] %s", *s.Priority, title)
+			}
+			if ref := is.repo.issueRef(s); ref != "" {
+				title += " " + ref
+			}
+			fmt.Fprintf(buf, "- [%s] %s\n", check, title)
+		}
+	}
+	buf.WriteString(syncEnd)
+	return buf.String()
+}
+
+// SyncProgress writes the computed progress and a checklist of sub-issues to the body of each loaded issue.
+// User-written content outside of the generated block is preserved.
+// If dryRun is set, planned edits are written to w as a diff and issues are not modified.
+// LoadTree must be called first.
+func (g *Github) SyncProgress(ctx context.Context, w io.Writer, dryRun bool) error {
+	if g.Offline && !dryRun {
+		return fmt.Errorf("cannot edit issues in offline mode")
+	}
+	var orgs []string
+	for name := range g.orgs {
+		orgs = append(orgs, name)
+	}
+	sort.Strings(orgs)
+	for _, oname := range orgs {
+		org := g.orgs[oname]
+		var repos []string
+		for name := range org.repos {
+			repos = append(repos, name)
+		}
+		sort.Strings(repos)
+		for _, rname := range repos {
+			if err := org.repos[rname].syncProgress(ctx, w, dryRun); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *ghRepo) syncProgress(ctx context.Context, w io.Writer, dryRun bool) error {
+	var nums []int
+	for num := range r.issues {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	g := r.org.g
+	edited := false
+	for _, num := range nums {
+		is := r.issues[num]
+		if is.node == nil {
+			continue
+		}
+		old := is.issue.GetBody()
+		body := replaceSyncBlock(old, is.syncBlock())
+		if body == old {
+			continue
+		}
+		if dryRun {
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(old),
+				B:        difflib.SplitLines(body),
+				FromFile: fmt.Sprintf("%s/%s#%d", r.org.name, r.name, num),
+				ToFile:   fmt.Sprintf("%s/%s#%d", r.org.name, r.name, num),
+				Context:  3,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err = io.WriteString(w, diff+"\n"); err != nil {
+				return err
+			}
+			continue
+		}
+		log.Printf("updating %s/%s#%d", r.org.name, r.name, num)
+		iss, err := g.editIssue(ctx, r.org.name, r.name, num, &github.IssueRequest{
+			Body: &body,
+		})
+		if err != nil {
+			return err
+		}
+		is.issue = iss
+		edited = true
+	}
+	if edited {
+		// cached issue list is stale now
+		return g.cache().drop(issuesKey(r.org.name, r.name))
+	}
+	return nil
+}
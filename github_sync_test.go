@@ -0,0 +1,47 @@
+package okrs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncBlock(t *testing.T) {
+	r := &ghRepo{org: &ghOrg{g: &Github{}, name: "org"}, name: "repo"}
+	done := &Node{
+		Title: "Done KR", Priority: pri(1),
+		Link:     Link{Title: "#2", URL: "https://github.com/org/repo/issues/2"},
+		Progress: &Progress{Done: 1, Total: 1},
+	}
+	open := &Node{
+		Title:    "Open KR",
+		Link:     Link{Title: "#3", URL: "https://github.com/org/other/issues/3"},
+		Progress: &Progress{Done: 0, Total: 1},
+	}
+	nd := &Node{Title: "Objective", Sub: []*Node{open, done}}
+	is := &ghIssue{repo: r, node: nd, subs: []*Node{open, done}}
+
+	block := is.syncBlock()
+	require.Equal(t, syncBegin+`
+**Progress:** 1/2
+
+- [x] [P1] Done KR #2
+- [ ] Open KR https://github.com/org/other/issues/3
+`+syncEnd, block)
+
+	const user = "Description\n\n**Parent:** #1\n"
+	body := replaceSyncBlock(user, block)
+	require.Equal(t, "Description\n\n**Parent:** #1\n\n"+block+"\n", body)
+
+	got, gblock := splitSyncBlock(body)
+	require.Equal(t, block, gblock)
+	require.Equal(t, "Description\n\n**Parent:** #1\n", got)
+
+	// replacing the block must not change the user content
+	done.Progress.Done = 0
+	body2 := replaceSyncBlock(body, is.syncBlock())
+	require.NotEqual(t, body, body2)
+	got, _ = splitSyncBlock(body2)
+	require.Equal(t, "Description\n\n**Parent:** #1\n", got)
+	require.Equal(t, body2, replaceSyncBlock(body2, is.syncBlock()))
+}
@@ -0,0 +1,477 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRoutes serves static JSON responses for Github API paths.
+// Only the first page of list requests returns the data.
+type fakeRoutes map[string]interface{}
+
+func (f fakeRoutes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, ok := f[r.URL.Path]
+	if !ok || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	if page := r.URL.Query().Get("page"); page != "" && page != "1" {
+		resp = []struct{}{}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func fakeIssue(org, repo string, num int, title, body string) *github.Issue {
+	return &github.Issue{
+		Number: github.Int(num), Title: github.String(title), Body: github.String(body),
+		URL:     github.String("https://api.github.com/repos/" + org + "/" + repo + "/issues/" + strconv.Itoa(num)),
+		HTMLURL: github.String("https://github.com/" + org + "/" + repo + "/issues/" + strconv.Itoa(num)),
+	}
+}
+
+// pullRequest marks the issue as a pull request, the same way issues API does.
+func pullRequest(is *github.Issue) *github.Issue {
+	is.PullRequestLinks = &github.PullRequestLinks{
+		URL: github.String(strings.Replace(is.GetURL(), "/issues/", "/pulls/", 1)),
+	}
+	return is
+}
+
+func TestParseIssueURL(t *testing.T) {
+	ghe := &Github{BaseURL: "https://ghe.example.com/api/v3"}
+	for _, c := range []struct {
+		g    *Github
+		url  string
+		org  string
+		repo string
+		num  int
+	}{
+		{url: "https://api.github.com/repos/org/repo/issues/12", org: "org", repo: "repo", num: 12},
+		{url: "https://github.com/org/repo/issues/3", org: "org", repo: "repo", num: 3},
+		{url: "https://github.com/org/repo/pull/4", org: "org", repo: "repo", num: 4},
+		{url: "https://github.com/org/repo"},
+		{url: "https://github.com/org/repo/issues/x"},
+		{url: "https://gitlab.com/org/repo/issues/5"},
+		{g: ghe, url: "https://ghe.example.com/api/v3/repos/org/repo/issues/6", org: "org", repo: "repo", num: 6},
+		{g: ghe, url: "https://ghe.example.com/org/repo/issues/7", org: "org", repo: "repo", num: 7},
+		{g: ghe, url: "https://github.com/org/repo/issues/8"},
+	} {
+		t.Run(c.url, func(t *testing.T) {
+			g := c.g
+			if g == nil {
+				g = &Github{}
+			}
+			org, repo, num, ok := g.parseIssueURL(c.url)
+			require.Equal(t, c.num != 0, ok)
+			require.Equal(t, c.org, org)
+			require.Equal(t, c.repo, repo)
+			require.Equal(t, c.num, num)
+		})
+	}
+}
+
+func TestEnterpriseURLs(t *testing.T) {
+	g := &Github{BaseURL: "https://ghe.example.com/api/v3"}
+	require.NoError(t, g.checkConfig())
+	require.Equal(t, "https://ghe.example.com", g.webURL())
+	require.Equal(t, "https://ghe.example.com/api/graphql", g.graphQLURL())
+	cli, err := g.client(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "https://ghe.example.com/api/v3/", cli.BaseURL.String())
+	require.Equal(t, "https://ghe.example.com/api/uploads/", cli.UploadURL.String())
+
+	g = &Github{BaseURL: "ghe.example.com"}
+	require.Error(t, g.checkConfig())
+}
+
+func TestProjects(t *testing.T) {
+	routes := fakeRoutes{
+		"/orgs/org/projects": []ghProjectState{
+			{Project: &github.Project{ID: github.Int64(7), Name: github.String("Other"), Number: github.Int(1)}, State: "open"},
+			{Project: &github.Project{ID: github.Int64(9), Name: github.String("2026-Q4 OKRs"), Number: github.Int(3)}, State: "closed"},
+			{Project: &github.Project{ID: github.Int64(10), Name: github.String("2026-Q4 OKRs"), Number: github.Int(2)}, State: "open"},
+		},
+		"/projects/9/columns": []*github.ProjectColumn{},
+		"/projects/10/columns": []*github.ProjectColumn{
+			{ID: github.Int64(100), Name: github.String("To do")},
+			{ID: github.Int64(101), Name: github.String("Done")},
+		},
+		"/projects/columns/100/cards": []*github.ProjectCard{
+			{ContentURL: github.String("https://api.github.com/repos/org/repo/issues/1")},
+			{Note: github.String("Some note\n\nDetails")},
+		},
+		"/projects/columns/101/cards": []*github.ProjectCard{
+			{ContentURL: github.String("https://api.github.com/repos/org2/other/issues/5")},
+		},
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+			fakeIssue("org", "repo", 2, "KR", "**Parent:** #1"),
+		},
+		"/repos/org2/other/issues": []*github.Issue{
+			fakeIssue("org2", "other", 5, "Shipped", "**Progress:** 1/4"),
+		},
+	}
+	g := newFakeGithub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orgs/org/projects" && r.URL.Query().Get("state") != "all" {
+			http.Error(w, "closed projects are not requested", http.StatusBadRequest)
+			return
+		}
+		routes.ServeHTTP(w, r)
+	}))
+	// open projects are preferred when selected by name
+	g.Orgs = []GHOrg{{Name: "org", Projects: []GHProject{{Name: "2026-q4 okrs"}}}}
+
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	root := tr.Root()
+	require.Equal(t, "2026-Q4 OKRs", root.Title)
+	require.Equal(t, "https://github.com/orgs/org/projects/2", root.Link.URL)
+	require.Equal(t, "2026-Q4", root.Period)
+	require.Len(t, root.Sub, 3)
+
+	obj := root.Sub[0]
+	require.Equal(t, "Objective", obj.Title)
+	require.Len(t, obj.Sub, 1)
+	require.Equal(t, "KR", obj.Sub[0].Title)
+	require.Nil(t, obj.Progress) // not in a done column
+
+	require.Equal(t, "Some note", root.Sub[1].Title)
+	require.Equal(t, "Details", root.Sub[1].Desc)
+
+	shipped := root.Sub[2]
+	require.Equal(t, "Shipped", shipped.Title)
+	require.Equal(t, StatusDone, shipped.Status)
+	require.Equal(t, &Progress{Done: 4, Total: 4}, shipped.Progress)
+
+	// closed projects can be selected by ID
+	g.orgs = nil
+	g.Orgs = []GHOrg{{Name: "org", Projects: []GHProject{{ID: 9}}}}
+	tr = NewTree()
+	require.NoError(t, g.LoadTree(context.Background(), tr))
+	require.Equal(t, "https://github.com/orgs/org/projects/3", tr.Root().Link.URL)
+}
+
+func TestProjectNotFound(t *testing.T) {
+	g := newFakeGithub(t, fakeRoutes{
+		"/orgs/org/projects": []*github.Project{},
+	})
+	g.Orgs = []GHOrg{{Name: "org", Projects: []GHProject{{ID: 3}}}}
+	err := g.LoadTree(context.Background(), NewTree())
+	require.EqualError(t, err, `cannot find project "3" in org`)
+}
+
+func TestIssueState(t *testing.T) {
+	closed := func(is *github.Issue, reason string) map[string]interface{} {
+		is.State = github.String("closed")
+		data, _ := json.Marshal(is)
+		var m map[string]interface{}
+		json.Unmarshal(data, &m)
+		m["state_reason"] = reason
+		return m
+	}
+	routes := fakeRoutes{
+		"/repos/org/repo/issues": []interface{}{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+			closed(fakeIssue("org", "repo", 2, "Done KR", "**Parent:** #1"), "completed"),
+			closed(fakeIssue("org", "repo", 3, "Dropped KR", "**Parent:** #1"), "not_planned"),
+			fakeIssue("org", "repo", 4, "Open KR", "**Parent:** #1\n\n**Progress:** 1/2"),
+			fakeIssue("org", "repo", 5, "Another KR", "**Parent:** #1"),
+			pullRequest(fakeIssue("org", "repo", 6, "Fix a bug", "**Parent:** #1")),
+			// fields from the body
+			fakeIssue("org", "repo", 7, "Paused objective", "**Parent:** #1\n**Status:** canceled\n**Kind:** objective"),
+		},
+	}
+	g := newFakeGithub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != "all" {
+			http.Error(w, "closed issues are not requested", http.StatusBadRequest)
+			return
+		}
+		routes.ServeHTTP(w, r)
+	}))
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	require.Equal(t, "repo", tr.Root().Title)
+	require.Len(t, tr.Root().Sub, 1)
+	obj := tr.Root().Sub[0]
+	require.Equal(t, "Objective", obj.Title)
+	require.Len(t, obj.Sub, 5, "pull requests must be skipped")
+	byTitle := make(map[string]*Node)
+	for _, s := range obj.Sub {
+		byTitle[s.Title] = s
+	}
+	require.Equal(t, StatusDone, byTitle["Done KR"].Status)
+	require.Equal(t, StatusCanceled, byTitle["Dropped KR"].Status)
+	require.Equal(t, &Progress{Done: 0, Total: 1}, byTitle["Another KR"].Progress)
+	require.Equal(t, StatusCanceled, byTitle["Paused objective"].Status)
+	require.Equal(t, KindObjective, byTitle["Paused objective"].Kind)
+	require.Equal(t, Progress{Done: 1, Total: 3}, obj.GetProgress())
+
+	require.NoError(t, Rollup{Mode: RollupWeighted}.Apply(obj))
+	require.Equal(t, Progress{Done: 50, Total: 100}, obj.GetProgress())
+	require.Nil(t, obj.Progress)
+}
+
+func TestCrossRepoParents(t *testing.T) {
+	g := newFakeGithub(t, fakeRoutes{
+		"/repos/org/okrs/issues": []*github.Issue{
+			fakeIssue("org", "okrs", 12, "Company objective", "Team results:\n\n* org/team-b#6\n* org/team-b#3\n"),
+		},
+		"/repos/org/team-b/issues": []*github.Issue{
+			fakeIssue("org", "team-b", 3, "Team KR", "**Parent:** org/okrs#12"),
+			fakeIssue("org", "team-b", 4, "Team KR 2", "**Parent:** https://github.com/org/okrs/issues/12"),
+			fakeIssue("org", "team-b", 5, "Partner KR", "**Parent:** other/plans#1"),
+			fakeIssue("org", "team-b", 6, "Listed KR", ""),
+		},
+		// fetched on demand, since the repository is not in the config
+		"/repos/other/plans/issues/1": fakeIssue("other", "plans", 1, "Partner objective", ""),
+	})
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "okrs"}, {Name: "team-b"}}}}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	var find func(n *Node, title string) *Node
+	find = func(n *Node, title string) *Node {
+		if n.Title == title {
+			return n
+		}
+		for _, s := range n.Sub {
+			if f := find(s, title); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+	titles := func(n *Node) []string {
+		var out []string
+		for _, s := range n.Sub {
+			out = append(out, s.Title)
+		}
+		return out
+	}
+	root := tr.Root()
+	obj := find(root, "Company objective")
+	require.NotNil(t, obj)
+	require.ElementsMatch(t, []string{"Team KR", "Team KR 2", "Listed KR"}, titles(obj))
+
+	partner := find(root, "Partner objective")
+	require.NotNil(t, partner)
+	require.Contains(t, root.Sub, partner)
+	require.Equal(t, []string{"Partner KR"}, titles(partner))
+
+	// issues linked to parents in other repositories are not listed as top-level issues of their repository
+	teamB := find(root, "team-b")
+	require.NotNil(t, teamB)
+	require.Empty(t, teamB.Sub)
+}
+
+func TestMissingParent(t *testing.T) {
+	g := newFakeGithub(t, fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "KR", "**Parent:** #7"),
+		},
+	})
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	err := g.LoadTree(context.Background(), NewTree())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot find parent of org/repo#1")
+}
+
+func TestIncorrectParent(t *testing.T) {
+	g := newFakeGithub(t, fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+			fakeIssue("org", "repo", 2, "KR", "**Parent:** #1"),
+			fakeIssue("org", "repo", 3, "Other objective", "Results:\n\n* #2\n"),
+		},
+	})
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	err := g.LoadTree(context.Background(), NewTree())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "incorrect parent of #2")
+}
+
+func TestLenientLoad(t *testing.T) {
+	g := newFakeGithub(t, fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+			fakeIssue("org", "repo", 2, "KR", "**Parent:** #1"),
+			fakeIssue("org", "repo", 3, "Other objective", "Results:\n\n* #2\n"),
+			fakeIssue("org", "repo", 4, "Orphan", "**Parent:** #7"),
+			fakeIssue("org", "repo", 5, "Cycle A", "**Parent:** #6"),
+			fakeIssue("org", "repo", 6, "Cycle B", "**Parent:** #5"),
+		},
+	})
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	tr := NewLenientTree()
+	require.NoError(t, g.LoadTree(context.Background(), tr))
+
+	var rules []string
+	for _, p := range (Lint{}).Check(tr) {
+		if p.Severity == SeverityError {
+			rules = append(rules, p.Rule)
+		}
+	}
+	require.ElementsMatch(t, []string{RuleConflictingParent, RuleUnresolvedParent, RuleCycle}, rules)
+
+	// issues with broken links stay in the tree
+	var titles []string
+	for _, n := range tr.Root().Sub {
+		titles = append(titles, n.Title)
+	}
+	require.Contains(t, titles, "Orphan")
+	require.Contains(t, titles, "Other objective")
+}
+
+func TestLabels(t *testing.T) {
+	labeled := func(is *github.Issue, labels ...string) *github.Issue {
+		for _, l := range labels {
+			is.Labels = append(is.Labels, github.Label{Name: github.String(l)})
+		}
+		return is
+	}
+	g := newFakeGithub(t, fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			labeled(fakeIssue("org", "repo", 1, "Objective", ""), "okr", "OKR:Objective", "p0"),
+			labeled(fakeIssue("org", "repo", 2, "[P2] KR", "**Parent:** #1"), "okr", "P1"),
+			labeled(fakeIssue("org", "repo", 3, "Dropped KR", "**Parent:** #1"), "okr", "wontfix"),
+			labeled(fakeIssue("org", "repo", 4, "Bug", "**Parent:** #1"), "bug"),
+			labeled(fakeIssue("org", "repo", 5, "Spam", ""), "okr", "spam"),
+			labeled(fakeIssue("org", "repo", 6, "Orphan KR", "**Parent:** #5"), "okr"),
+		},
+	})
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	g.Labels = GHLabels{
+		Priority: map[string]int{"P0": 0, "P1": 1},
+		Status:   map[string]Status{"wontfix": StatusCanceled},
+		Kind:     map[string]Kind{"okr:objective": KindObjective},
+		Include:  []string{"okr"},
+		Exclude:  []string{"spam"},
+	}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	root := tr.Root()
+	root.Sort()
+	require.Len(t, root.Sub, 2)
+	obj := root.Sub[0]
+	require.Equal(t, "Objective", obj.Title)
+	require.Equal(t, pri(0), obj.Priority)
+	require.Equal(t, KindObjective, obj.Kind)
+
+	// the parent is excluded, so the issue is kept at the top level
+	require.Equal(t, "Orphan KR", root.Sub[1].Title)
+
+	obj.Sort()
+	require.Len(t, obj.Sub, 2)
+	kr := obj.Sub[0]
+	require.Equal(t, "KR", kr.Title)
+	require.Equal(t, pri(2), kr.Priority, "priority in the title takes precedence")
+	require.Equal(t, Kind(""), kr.Kind)
+	require.Equal(t, StatusCanceled, obj.Sub[1].Status)
+}
+
+func TestIssueOwners(t *testing.T) {
+	assign := func(is *github.Issue, logins ...string) *github.Issue {
+		for _, l := range logins {
+			is.Assignees = append(is.Assignees, &github.User{Login: github.String(l)})
+		}
+		return is
+	}
+	legacy := fakeIssue("org", "repo", 3, "Legacy KR", "**Parent:** #1")
+	legacy.Assignee = &github.User{Login: github.String("carol")}
+	routes := fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			assign(fakeIssue("org", "repo", 1, "Objective A", "**Team:** platform"), "alice"),
+			assign(fakeIssue("org", "repo", 2, "KR A", "**Parent:** #1\n\n**Owner:** @dave"), "bob", "Alice"),
+			legacy,
+			fakeIssue("org", "repo", 4, "Objective B", ""),
+			assign(fakeIssue("org", "repo", 5, "KR B", "**Parent:** #4\n\n**Progress:** 1/2"), "bob"),
+			fakeIssue("org", "repo", 6, "KR B2", "**Parent:** #4\n\n**Progress:** 2/2"),
+		},
+	}
+	g := newFakeGithub(t, routes)
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	byTitle := make(map[string]*Node)
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		byTitle[n.Title] = n
+		for _, s := range n.Sub {
+			walk(s)
+		}
+	}
+	walk(tr.Root())
+	require.Equal(t, []string{"alice"}, byTitle["Objective A"].Owners)
+	require.Equal(t, "platform", byTitle["Objective A"].Team)
+	// owners from the body are merged with assignees, ignoring the case
+	require.ElementsMatch(t, []string{"bob", "Alice", "dave"}, byTitle["KR A"].Owners)
+	require.Equal(t, []string{"carol"}, byTitle["Legacy KR"].Owners)
+
+	// per-owner view keeps ancestors and the progress of partially matched nodes
+	path := filepath.Join(t.TempDir(), "bob.json")
+	err = Output{Path: path, Owner: "@bob"}.WriteTree(tr)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var root Node
+	require.NoError(t, json.Unmarshal(data, &root))
+	require.Equal(t, "repo", root.Title)
+	require.Len(t, root.Sub, 2)
+	var titles []string
+	for _, obj := range root.Sub {
+		require.Len(t, obj.Sub, 1, obj.Title)
+		titles = append(titles, obj.Sub[0].Title)
+	}
+	require.ElementsMatch(t, []string{"KR A", "KR B"}, titles)
+	for _, obj := range root.Sub {
+		if obj.Title == "Objective B" {
+			require.Equal(t, &Progress{Done: 1, Total: 2}, obj.Progress)
+		}
+	}
+
+	// teams match as owners
+	require.Equal(t, &Node{}, tr.Filter(func(n *Node) bool { return n.HasOwner("nobody") }).Root())
+	view := tr.Filter(func(n *Node) bool { return n.HasOwner("Platform") })
+	require.Len(t, view.Root().Sub, 1)
+	require.Equal(t, "Objective A", view.Root().Sub[0].Title)
+	require.Len(t, view.Root().Sub[0].Sub, 2, "sub-tree of the matched node is kept")
+}
+
+func TestGithubAfterMarkdown(t *testing.T) {
+	g := newFakeGithub(t, fakeRoutes{
+		"/repos/org/repo/issues": []*github.Issue{
+			fakeIssue("org", "repo", 1, "Objective", ""),
+		},
+	})
+	g.Orgs = []GHOrg{{Name: "org", Repos: []GHRepo{{Name: "repo"}}}}
+
+	tr := NewTree()
+	require.NoError(t, ParseMDTree(strings.NewReader("# Company objective\n\n* KR\n"), tr))
+	require.NoError(t, g.LoadTree(context.Background(), tr))
+
+	root := tr.Root()
+	require.Len(t, root.Sub, 2)
+	require.Equal(t, "Company objective", root.Sub[0].Title)
+	require.Len(t, root.Sub[0].Sub, 1, "repository must not be attached to the objective")
+	require.Equal(t, "repo", root.Sub[1].Title)
+	require.Equal(t, "Objective", root.Sub[1].Sub[0].Title)
+}
@@ -0,0 +1,316 @@
+package okrs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Gitlab loads OKRs from issues and epics of a GitLab instance.
+//
+// Parents are taken from the native hierarchy: epics are nested by their parent epic, and issues are
+// attached to their epic. Issues without an epic can reference a parent with "**Parent:** #N" in the
+// description, like on Github. Items listed in the description become children of the item, either as
+// links to other issues or epics, or as new nodes. Issues are referenced with "#N" or "group/project#N",
+// and epics with "&N" or "group&N". Since epics don't have a project, "#N" in an epic only references
+// an issue if a single project of the group has it.
+type Gitlab struct {
+	// URL is the address of the GitLab instance. Defaults to https://gitlab.com.
+	URL   string `json:"url,omitempty" yaml:"url,omitempty"`
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// TokenEnv is the name of the environment variable with the API token.
+	TokenEnv string      `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	Groups   []GLGroup   `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Projects []GLProject `json:"projects,omitempty" yaml:"projects,omitempty"`
+
+	hcli *http.Client
+}
+
+// GLGroup selects issues of all projects of the group, for example "org/team".
+type GLGroup struct {
+	Path string `json:"path" yaml:"path"`
+	// Epics also loads epics of the group. Epics are only available in GitLab Premium.
+	Epics bool `json:"epics,omitempty" yaml:"epics,omitempty"`
+}
+
+// GLProject selects issues of a single project, for example "org/team/okrs".
+type GLProject struct {
+	Path string `json:"path" yaml:"path"`
+}
+
+type glUser struct {
+	Username string `json:"username"`
+}
+
+type glMilestone struct {
+	Title   string `json:"title"`
+	DueDate string `json:"due_date"`
+}
+
+// glItem contains fields shared by issues and epics.
+type glItem struct {
+	ID          int          `json:"id"`
+	IID         int          `json:"iid"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	State       string       `json:"state"`
+	WebURL      string       `json:"web_url"`
+	DueDate     string       `json:"due_date"`
+	StartDate   string       `json:"start_date"`
+	Assignees   []glUser     `json:"assignees"`
+	Milestone   *glMilestone `json:"milestone"`
+}
+
+type glIssue struct {
+	glItem
+	ProjectID int `json:"project_id"`
+	Epic      *struct {
+		ID int `json:"id"`
+	} `json:"epic"`
+}
+
+type glEpic struct {
+	glItem
+	GroupID  int  `json:"group_id"`
+	ParentID *int `json:"parent_id"`
+}
+
+func (g *Gitlab) baseURL() string {
+	if g.URL == "" {
+		return "https://gitlab.com"
+	}
+	return strings.TrimSuffix(g.URL, "/")
+}
+
+// client returns a client of GitLab API that authorizes requests with a given token.
+func (g *Gitlab) client(tok string) *restClient {
+	return &restClient{
+		name: "gitlab", base: g.baseURL() + "/api/v4", hcli: g.hcli,
+		auth: func(req *http.Request) {
+			if tok != "" {
+				req.Header.Set("PRIVATE-TOKEN", tok)
+			}
+		},
+		page: func(page int) string {
+			return fmt.Sprintf("per_page=100&page=%d", page)
+		},
+		hasNext: func(resp *http.Response) bool {
+			return resp.Header.Get("X-Next-Page") != ""
+		},
+	}
+}
+
+// asNode creates a node for an issue or epic, including fields from its description.
+func (it *glItem) asNode(tr *Tree, ref string) (*Node, *Node, error) {
+	title, pri := issueTitle(it.Title)
+	nd := tr.NewNode(Node{
+		ID: it.WebURL, Title: title,
+		Link:     Link{Title: ref, URL: it.WebURL},
+		Priority: pri,
+	})
+	for _, u := range it.Assignees {
+		nd.Owners = appendOwners(nd.Owners, u.Username)
+	}
+	if m := it.Milestone; m != nil {
+		nd.Period = parsePeriod(m.Title)
+		nd.Due, _ = parseDate(m.DueDate)
+	}
+	if it.DueDate != "" {
+		nd.Due, _ = parseDate(it.DueDate)
+	}
+	if it.StartDate != "" {
+		nd.Start, _ = parseDate(it.StartDate)
+	}
+	if it.State == "closed" {
+		nd.Status = StatusDone
+		markDone(nd)
+	}
+	local := NewTree()
+	if err := ParseMDTree(strings.NewReader(it.Description), local); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse %s: %v", it.WebURL, err)
+	}
+	lroot := local.Root()
+	if lroot != nil {
+		mergeLocal(nd, lroot)
+	}
+	return nd, lroot, nil
+}
+
+// glContainer is a group or a project that holds top-level items.
+type glContainer struct {
+	node   *Node
+	issues []*glIssue
+	epics  []*glEpic
+}
+
+func (g *Gitlab) container(tr *Tree, path string) *glContainer {
+	return &glContainer{node: tr.NewNode(Node{
+		Title: path,
+		Link:  Link{Title: path, URL: g.baseURL() + "/" + path},
+	})}
+}
+
+// LoadTree reads issues and epics of all configured groups and projects.
+func (g *Gitlab) LoadTree(ctx context.Context, tr *Tree) error {
+	tok, err := configToken(g.Token, g.TokenEnv)
+	if err != nil {
+		return err
+	}
+	cli := g.client(tok)
+	var conts []*glContainer
+	for _, grp := range g.Groups {
+		c := g.container(tr, grp.Path)
+		id := url.PathEscape(grp.Path)
+		if err := cli.list(ctx, "groups/"+id+"/issues?state=all", &c.issues); err != nil {
+			return err
+		}
+		if grp.Epics {
+			if err := cli.list(ctx, "groups/"+id+"/epics?state=all", &c.epics); err != nil {
+				return err
+			}
+		}
+		conts = append(conts, c)
+	}
+	for _, p := range g.Projects {
+		c := g.container(tr, p.Path)
+		if err := cli.list(ctx, "projects/"+url.PathEscape(p.Path)+"/issues?state=all", &c.issues); err != nil {
+			return err
+		}
+		conts = append(conts, c)
+	}
+	return g.asTree(ctx, tr, conts)
+}
+
+// itemPath returns the path of the project or the group of an item from its web URL,
+// for example "org/team/app" for ".../org/team/app/-/issues/1" and "org/team" for ".../groups/org/team/-/epics/1".
+func (g *Gitlab) itemPath(webURL string) string {
+	p := strings.TrimPrefix(webURL, g.baseURL())
+	if p == webURL {
+		u, err := url.Parse(webURL)
+		if err != nil {
+			return ""
+		}
+		p = u.Path
+	}
+	i := strings.Index(p, "/-/")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p[:i], "/"), "groups/")
+}
+
+// reGLRef matches GitLab references to issues and epics: "#N", "&N", "group/project#N" and "group&N".
+var reGLRef = regexp.MustCompile(`^([\w.-]+(?:/[\w.-]+)*)?([#&])(\d+)$`)
+
+// glIndex finds GitLab issues and epics referenced from descriptions of other items.
+// Scopes of issues are paths of their projects, and scopes of epics are paths of their groups.
+type glIndex struct {
+	issues map[string]*trackerIssue // by "project#iid"
+	epics  map[string]*trackerIssue // by "group&iid"
+	isEpic map[*trackerIssue]bool
+	byURL  map[string]*trackerIssue
+}
+
+func (idx *glIndex) lookup(ctx context.Context, from *trackerIssue, l Link) (*trackerIssue, bool, error) {
+	sub := reGLRef.FindStringSubmatch(l.Title)
+	if len(sub) == 0 {
+		return idx.byURL[l.URL], false, nil
+	}
+	ref, num := sub[1], sub[3]
+	if sub[2] == "&" {
+		if ref == "" {
+			// epics of the project's group are referenced from issues
+			ref = from.scope
+			if !idx.isEpic[from] {
+				ref = path.Dir(ref)
+			}
+		}
+		return idx.epics[ref+"&"+num], false, nil
+	}
+	if ref != "" {
+		return idx.issues[ref+"#"+num], false, nil
+	} else if !idx.isEpic[from] {
+		return idx.issues[from.scope+"#"+num], false, nil
+	}
+	// epics don't have a project - "#N" is only resolved if one project of the group has such issue
+	var found *trackerIssue
+	for key, is := range idx.issues {
+		if strings.HasPrefix(key, from.scope+"/") && strings.HasSuffix(key, "#"+num) {
+			if found != nil {
+				return nil, false, nil
+			}
+			found = is
+		}
+	}
+	return found, false, nil
+}
+
+func (g *Gitlab) asTree(ctx context.Context, tr *Tree, conts []*glContainer) error {
+	idx := &glIndex{
+		issues: make(map[string]*trackerIssue),
+		epics:  make(map[string]*trackerIssue),
+		isEpic: make(map[*trackerIssue]bool),
+		byURL:  make(map[string]*trackerIssue),
+	}
+	res := &issueResolver{tr: tr, tracker: idx}
+	add := func(c *glContainer, it *glItem, ref string) (*trackerIssue, error) {
+		if is, ok := idx.byURL[it.WebURL]; ok {
+			return is, nil // listed both in the group and the project
+		}
+		nd, local, err := it.asNode(tr, ref)
+		if err != nil {
+			return nil, err
+		}
+		is := &trackerIssue{node: nd, local: local, scope: g.itemPath(it.WebURL), top: c.node}
+		idx.byURL[it.WebURL] = is
+		res.add(is)
+		return is, nil
+	}
+	var (
+		epics   = make(map[int]*trackerIssue) // by global epic id
+		parents = make(map[*trackerIssue]int) // epic ids of native parents
+	)
+	for _, c := range conts {
+		for _, e := range c.epics {
+			is, err := add(c, &e.glItem, fmt.Sprintf("&%d", e.IID))
+			if err != nil {
+				return err
+			}
+			epics[e.ID] = is
+			idx.epics[is.scope+"&"+strconv.Itoa(e.IID)] = is
+			idx.isEpic[is] = true
+			if e.ParentID != nil {
+				parents[is] = *e.ParentID
+			}
+		}
+	}
+	for _, c := range conts {
+		for _, it := range c.issues {
+			is, err := add(c, &it.glItem, fmt.Sprintf("#%d", it.IID))
+			if err != nil {
+				return err
+			}
+			idx.issues[is.scope+"#"+strconv.Itoa(it.IID)] = is
+			if it.Epic != nil {
+				parents[is] = it.Epic.ID
+			}
+		}
+	}
+	// native hierarchy takes precedence over parent links in descriptions
+	for is, id := range parents {
+		is.parent = epics[id]
+	}
+	if err := res.resolve(ctx); err != nil {
+		return err
+	}
+	nodes := make([]*Node, 0, len(conts))
+	for _, c := range conts {
+		nodes = append(nodes, c.node)
+	}
+	return addContainers(tr, nodes)
+}
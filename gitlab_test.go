@@ -0,0 +1,112 @@
+package okrs
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitlab(t *testing.T) {
+	fake := &fakeREST{
+		header: "PRIVATE-TOKEN", token: "secret",
+		query: map[string]string{"state": "all"},
+		next: func(w http.ResponseWriter, r *http.Request, page int) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page))
+		},
+	}
+	srv := newFakeREST(t, fake)
+	u := func(path string) string { return srv.URL + path }
+
+	fake.pages["/api/v4/groups/org%2Fteam/epics"] = []interface{}{[]map[string]interface{}{
+		{"id": 100, "iid": 1, "title": "[P0] Grow revenue", "state": "opened", "web_url": u("/groups/org/team/-/epics/1")},
+		{"id": 101, "iid": 2, "parent_id": 100, "title": "Sales", "state": "opened", "web_url": u("/groups/org/team/-/epics/2"),
+			"description": "**Team:** sales"},
+		{"id": 102, "iid": 3, "title": "Marketing", "state": "opened", "web_url": u("/groups/org/team/-/epics/3"),
+			"description": "Results:\n\n- [ ] Launch #8\n"},
+	}}
+	fake.pages["/api/v4/groups/org%2Fteam/issues"] = []interface{}{
+		[]map[string]interface{}{
+			{"id": 200, "iid": 5, "project_id": 10, "title": "Close deals", "state": "opened",
+				"web_url": u("/org/team/app/-/issues/5"), "description": "**Progress:** 3/10",
+				"epic": map[string]interface{}{"id": 101}, "assignees": []map[string]string{{"username": "alice"}}},
+		},
+		[]map[string]interface{}{
+			{"id": 201, "iid": 6, "project_id": 10, "title": "Hire", "state": "closed",
+				"web_url": u("/org/team/app/-/issues/6"), "milestone": map[string]string{"title": "2026-Q4"}},
+			{"id": 202, "iid": 7, "project_id": 10, "title": "Onboard", "state": "opened",
+				"web_url": u("/org/team/app/-/issues/7"), "description": "**Parent:** #6"},
+			{"id": 203, "iid": 8, "project_id": 10, "title": "Launch", "state": "opened",
+				"web_url": u("/org/team/app/-/issues/8")},
+			{"id": 204, "iid": 9, "project_id": 10, "title": "Ads", "state": "opened",
+				"web_url": u("/org/team/app/-/issues/9"), "description": "**Parent:** &3"},
+		},
+	}
+	fake.pages["/api/v4/projects/org%2Fother/issues"] = []interface{}{[]map[string]interface{}{
+		{"id": 300, "iid": 1, "project_id": 11, "title": "Standalone", "state": "opened",
+			"web_url": u("/org/other/-/issues/1"), "due_date": "2026-12-31",
+			"description": "**Kind:** objective\n\n- [x] Write docs\n- [ ] Linked KR #2\n- [ ] Campaign org/team&3\n"},
+		{"id": 301, "iid": 2, "project_id": 11, "title": "Linked KR", "state": "opened",
+			"web_url": u("/org/other/-/issues/2"), "description": "**Status:** canceled"},
+	}}
+
+	t.Setenv("OKRS_GITLAB_TOKEN", "secret")
+	g := &Gitlab{
+		URL: srv.URL, TokenEnv: "OKRS_GITLAB_TOKEN",
+		Groups:   []GLGroup{{Path: "org/team", Epics: true}},
+		Projects: []GLProject{{Path: "org/other"}},
+	}
+	tr := NewTree()
+	err := g.LoadTree(context.Background(), tr)
+	require.NoError(t, err)
+
+	root := tr.Root()
+	require.Len(t, root.Sub, 2)
+	team, other := root.Sub[0], root.Sub[1]
+	require.Equal(t, Link{Title: "org/team", URL: u("/org/team")}, team.Link)
+	require.Equal(t, "org/other", other.Title)
+
+	require.Len(t, team.Sub, 2)
+	obj := team.Sub[0]
+	require.Equal(t, "Grow revenue", obj.Title)
+	require.Equal(t, pri(0), obj.Priority)
+	require.Equal(t, Link{Title: "&1", URL: u("/groups/org/team/-/epics/1")}, obj.Link)
+	require.Len(t, obj.Sub, 1)
+	sales := obj.Sub[0]
+	require.Equal(t, "sales", sales.Team)
+	require.Len(t, sales.Sub, 1)
+	kr := sales.Sub[0]
+	require.Equal(t, "Close deals", kr.Title)
+	require.Equal(t, []string{"alice"}, kr.Owners)
+	require.Equal(t, &Progress{Done: 3, Total: 10}, kr.Progress)
+
+	hire := team.Sub[1]
+	require.Equal(t, "Hire", hire.Title)
+	require.Equal(t, StatusDone, hire.Status)
+	require.Equal(t, "2026-Q4", hire.Period)
+	require.Len(t, hire.Sub, 1)
+	require.Equal(t, "Onboard", hire.Sub[0].Title)
+	require.Equal(t, &Progress{Done: 0, Total: 1}, hire.Sub[0].Progress)
+
+	require.Len(t, other.Sub, 1)
+	obj2 := other.Sub[0]
+	require.Equal(t, date("2026-12-31"), obj2.Due)
+	require.Equal(t, KindObjective, obj2.Kind)
+	require.Len(t, obj2.Sub, 3, "checklist items must be attached")
+	linked, marketing, docs := obj2.Sub[0], obj2.Sub[1], obj2.Sub[2]
+	require.Equal(t, Link{Title: "#2", URL: u("/org/other/-/issues/2")}, linked.Link)
+	require.Equal(t, StatusCanceled, linked.Status)
+	require.Equal(t, "Write docs", docs.Title)
+	require.Equal(t, Progress{Done: 1, Total: 2}, obj2.GetProgress())
+
+	// "&N" from an issue references an epic of its group, "#N" from an epic - an issue of the group
+	require.Equal(t, "Marketing", marketing.Title)
+	require.Equal(t, Link{Title: "&3", URL: u("/groups/org/team/-/epics/3")}, marketing.Link)
+	require.Len(t, marketing.Sub, 2)
+	require.ElementsMatch(t, []string{"Launch", "Ads"}, []string{marketing.Sub[0].Title, marketing.Sub[1].Title})
+
+	g.TokenEnv = "OKRS_NO_TOKEN"
+	require.Error(t, g.LoadTree(context.Background(), NewTree()))
+}
@@ -0,0 +1,260 @@
+package okrs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// History is a store of progress snapshots of the OKR tree.
+type History struct {
+	// Path is either a directory with one file per snapshot, or a single JSON file with all snapshots.
+	Path string `json:"path" yaml:"path"`
+}
+
+// Snapshot is a progress of all nodes of the tree at a given time.
+type Snapshot struct {
+	Time  time.Time      `json:"time"`
+	Nodes []NodeProgress `json:"nodes"`
+}
+
+// NodeProgress is a progress of a single node in the snapshot.
+type NodeProgress struct {
+	// Key identifies the node between snapshots. It's either node ID, URL or a path of titles.
+	Key      string   `json:"key"`
+	Title    string   `json:"title,omitempty"`
+	Progress Progress `json:"progress"`
+}
+
+func (h *History) isFile() bool {
+	return filepath.Ext(h.Path) == ".json"
+}
+
+// nodeKey returns a key that identifies the node between snapshots.
+func nodeKey(path []string, n *Node) string {
+	if n.ID != "" {
+		return n.ID
+	} else if n.Link.URL != "" {
+		return n.Link.URL
+	}
+	return strings.Join(append(path, nodeName(n)), " / ")
+}
+
+// walkKeys calls the function for each node in the tree with a key that identifies it.
+func walkKeys(root *Node, fnc func(key string, n *Node)) {
+	seen := make(map[*Node]struct{})
+	var walk func(path []string, n *Node)
+	walk = func(path []string, n *Node) {
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		if !n.isProxyNode() {
+			fnc(nodeKey(path, n), n)
+			path = append(path, nodeName(n))
+		}
+		for _, s := range n.Sub {
+			walk(path[:len(path):len(path)], s)
+		}
+	}
+	if root != nil {
+		walk(nil, root)
+	}
+}
+
+// NewSnapshot records the progress of all nodes in the tree.
+func NewSnapshot(tr *Tree, t time.Time) Snapshot {
+	s := Snapshot{Time: t.UTC()}
+	walkKeys(tr.Root(), func(key string, n *Node) {
+		s.Nodes = append(s.Nodes, NodeProgress{
+			Key: key, Title: nodeName(n),
+			Progress: n.GetProgress(),
+		})
+	})
+	return s
+}
+
+// Load reads all snapshots from the store, ordered by time.
+func (h *History) Load() ([]Snapshot, error) {
+	var out []Snapshot
+	if h.isFile() {
+		f, err := os.Open(h.Path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if err = json.NewDecoder(f).Decode(&out); err != nil {
+			return nil, err
+		}
+	} else {
+		files, err := filepath.Glob(filepath.Join(h.Path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range files {
+			var s Snapshot
+			if err := readJSON(name, &s); err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
+
+// Record adds a snapshot to the store.
+func (h *History) Record(s Snapshot) error {
+	if !h.isFile() {
+		if err := os.MkdirAll(h.Path, 0755); err != nil {
+			return err
+		}
+		// file names are only used to keep snapshots apart, the time is read from the content
+		base := filepath.Join(h.Path, s.Time.Format("20060102T150405.000000000Z"))
+		name := base + ".json"
+		for i := 1; ; i++ {
+			if _, err := os.Stat(name); os.IsNotExist(err) {
+				break
+			} else if err != nil {
+				return err
+			}
+			name = fmt.Sprintf("%s-%d.json", base, i)
+		}
+		return writeJSON(name, s)
+	}
+	arr, err := h.Load()
+	if err != nil {
+		return err
+	}
+	arr = append(arr, s)
+	if dir := filepath.Dir(h.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return writeJSON(h.Path, arr)
+}
+
+// Point is a progress of the node at a given time.
+type Point struct {
+	Time     time.Time `json:"time"`
+	Progress Progress  `json:"progress"`
+}
+
+// Series is a progress history of a single node.
+type Series struct {
+	Key    string  `json:"key"`
+	Title  string  `json:"title,omitempty"`
+	Points []Point `json:"points"`
+}
+
+// Fractions returns progress values of the series in [0, 1] range.
+func (s Series) Fractions() []float64 {
+	out := make([]float64, 0, len(s.Points))
+	for _, p := range s.Points {
+		out = append(out, p.Progress.Fraction())
+	}
+	return out
+}
+
+// NewSeries groups progress of nodes from all snapshots by the node key.
+// Series are returned in the order the nodes first appeared in snapshots.
+func NewSeries(snaps []Snapshot) []Series {
+	var out []Series
+	index := make(map[string]int)
+	for _, s := range snaps {
+		for _, n := range s.Nodes {
+			i, ok := index[n.Key]
+			if !ok {
+				i = len(out)
+				index[n.Key] = i
+				out = append(out, Series{Key: n.Key})
+			}
+			out[i].Title = n.Title
+			out[i].Points = append(out[i].Points, Point{Time: s.Time, Progress: n.Progress})
+		}
+	}
+	return out
+}
+
+// AnnotateTrends attaches the progress history to the nodes of the tree, so it can be rendered by writers.
+// The current progress of the node is added as the last point.
+func AnnotateTrends(tr *Tree, snaps []Snapshot) {
+	byKey := make(map[string]Series)
+	for _, s := range NewSeries(snaps) {
+		byKey[s.Key] = s
+	}
+	walkKeys(tr.Root(), func(key string, n *Node) {
+		s, ok := byKey[key]
+		if !ok {
+			return
+		}
+		n.trend = append(s.Fractions(), n.GetProgress().Fraction())
+	})
+}
+
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders a series of values in [0, 1] range as a string of block characters.
+func Sparkline(vals []float64) string {
+	out := make([]rune, 0, len(vals))
+	for _, v := range vals {
+		if v < 0 {
+			v = 0
+		} else if v > 1 {
+			v = 1
+		}
+		i := int(math.Round(v * float64(len(sparkChars)-1)))
+		out = append(out, sparkChars[i])
+	}
+	return string(out)
+}
+
+func readJSON(name string, out interface{}) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewDecoder(f).Decode(out)
+}
+
+// writeJSON atomically writes a JSON file by writing to a temporary file first.
+func writeJSON(name string, data interface{}) error {
+	return writeFileAtomic(name, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(data)
+	})
+}
+
+// writeFileAtomic writes a file by writing to a temporary file in the same directory and renaming it.
+func writeFileAtomic(name string, fnc func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = fnc(f)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package okrs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	for _, name := range []string{"history", "history.json"} {
+		t.Run(name, func(t *testing.T) {
+			h := &History{Path: filepath.Join(t.TempDir(), name)}
+			kr := &Node{Title: "KR", Progress: &Progress{Done: 10, Total: 100}}
+			tr := &Tree{root: &Node{Title: "Objective", Sub: []*Node{kr}}}
+
+			t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+			for i, perc := range []int{10, 40, 70} {
+				kr.Progress.Done = perc
+				err := h.Record(NewSnapshot(tr, t0.Add(time.Duration(i)*24*time.Hour)))
+				require.NoError(t, err)
+			}
+			snaps, err := h.Load()
+			require.NoError(t, err)
+			require.Len(t, snaps, 3)
+
+			series := NewSeries(snaps)
+			require.Len(t, series, 2)
+			require.Equal(t, "Objective / KR", series[1].Key)
+			require.Equal(t, []float64{0.1, 0.4, 0.7}, series[1].Fractions())
+
+			kr.Progress.Done = 100
+			AnnotateTrends(tr, snaps)
+			require.Equal(t, "▂▄▆█", Sparkline(kr.trend))
+		})
+	}
+}
+
+func TestHistorySameTime(t *testing.T) {
+	h := &History{Path: filepath.Join(t.TempDir(), "history")}
+	tr := &Tree{root: &Node{Title: "Objective", Progress: &Progress{Done: 1, Total: 2}}}
+
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for _, ts := range []time.Time{t0, t0.Add(time.Millisecond), t0.Add(time.Millisecond)} {
+		err := h.Record(NewSnapshot(tr, ts))
+		require.NoError(t, err)
+	}
+	snaps, err := h.Load()
+	require.NoError(t, err)
+	require.Len(t, snaps, 3)
+	require.Equal(t, t0, snaps[0].Time)
+	require.Equal(t, t0.Add(time.Millisecond), snaps[2].Time)
+}
@@ -0,0 +1,139 @@
+package okrs
+
+import (
+	"html/template"
+	"io"
+
+	"gopkg.in/russross/blackfriday.v2"
+)
+
+func init() {
+	RegisterTreeWriter(TreeWriterDesc{
+		Name: "html", Ext: "html",
+		Write: WriteHTMLTree,
+	})
+}
+
+type htmlNode struct {
+	Title    string
+	Desc     template.HTML
+	Link     Link
+	Links    []Link
+	Priority *int
+	Metric   *Metric
+	Owners   []string
+	Team     string
+	Period   string
+	Status   Status
+	Due      string
+	Progress Progress
+	Perc     int
+	Trend    string
+	Sub      []htmlNode
+}
+
+// mdToHTML renders a Markdown description as HTML, skipping any raw HTML in the source.
+func mdToHTML(s string) template.HTML {
+	if s == "" {
+		return ""
+	}
+	r := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+		Flags: blackfriday.SkipHTML | blackfriday.Safelink | blackfriday.NofollowLinks,
+	})
+	return template.HTML(blackfriday.Run([]byte(s), blackfriday.WithRenderer(r)))
+}
+
+func asHTMLNode(n *Node) htmlNode {
+	h := htmlNode{
+		Title:    n.Title,
+		Desc:     mdToHTML(n.Desc),
+		Link:     n.Link,
+		Links:    n.Links,
+		Priority: n.Priority,
+		Metric:   n.Metric,
+		Owners:   n.Owners,
+		Team:     n.Team,
+		Period:   n.Period,
+		Status:   n.Status,
+		Progress: n.GetProgress(),
+	}
+	if h.Title == "" {
+		h.Title = n.Link.Title
+	}
+	if n.Due != nil {
+		h.Due = formatDate(*n.Due)
+	}
+	if len(n.trend) > 1 {
+		h.Trend = Sparkline(n.trend)
+	}
+	if p := h.Progress; p.Total > 0 {
+		h.Perc = 100 * p.Done / p.Total
+	}
+	for _, s := range n.Sub {
+		h.Sub = append(h.Sub, asHTMLNode(s))
+	}
+	return h
+}
+
+func WriteHTMLTree(w io.Writer, tree *Node) error {
+	return htmlTemplate.Execute(w, asHTMLNode(acyclic(tree)))
+}
+
+var htmlTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}OKRs{{end}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 2em; color: #333; }
+details { margin: 0.3em 0 0.3em 1.2em; }
+summary { cursor: pointer; }
+.leaf { margin: 0.3em 0 0.3em 2.2em; }
+.title { font-weight: bold; }
+.pri { display: inline-block; padding: 0 0.4em; border-radius: 0.3em; font-size: 0.8em; color: #fff; background: #757575; }
+.pri-0 { background: #c62828; }
+.pri-1 { background: #ef6c00; }
+.pri-2 { background: #f9a825; }
+.bar { display: inline-block; width: 8em; height: 0.7em; background: #e0e0e0; border-radius: 0.3em; vertical-align: middle; overflow: hidden; }
+.bar > div { height: 100%; background: #66bb6a; }
+.perc, .metric { font-size: 0.8em; color: #757575; }
+.trend { color: #66bb6a; letter-spacing: -0.1em; }
+.desc { margin-left: 1.2em; color: #555; }
+.links { font-size: 0.8em; }
+.owner { font-size: 0.8em; color: #6a1b9a; }
+a { color: #1565c0; }
+</style>
+</head>
+<body>
+{{template "node" .}}
+</body>
+</html>
+{{define "head"}}
+{{- if .Priority}}<span class="pri pri-{{.Priority}}">P{{.Priority}}</span> {{end -}}
+<span class="title">{{.Title}}</span>
+{{- with .Link}}{{if .URL}} <a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}link{{end}}</a>{{end}}{{end}}
+{{- if .Progress.Total}} <span class="bar"><div style="width: {{.Perc}}%"></div></span> <span class="perc">{{.Progress.Done}}/{{.Progress.Total}}</span>{{end}}
+{{- with .Trend}} <span class="trend" title="progress history">{{.}}</span>{{end}}
+{{- with .Metric}} <span class="metric">{{.String}}</span>{{end}}
+{{- range .Owners}} <span class="owner">@{{.}}</span>{{end}}
+{{- with .Team}} <span class="owner">{{.}}</span>{{end}}
+{{- with .Period}} <span class="metric">{{.}}</span>{{end}}
+{{- with .Status}} <span class="metric">{{.}}</span>{{end}}
+{{- with .Due}} <span class="metric">due {{.}}</span>{{end}}
+{{- end}}
+{{define "body"}}
+{{- if .Desc}}<div class="desc">{{.Desc}}</div>{{end}}
+{{- if .Links}}<div class="links desc">{{range .Links}}<a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a> {{end}}</div>{{end}}
+{{- end}}
+{{define "node"}}
+{{- if .Sub}}
+<details open>
+<summary>{{template "head" .}}</summary>
+{{template "body" .}}
+{{- range .Sub}}{{template "node" .}}{{end}}
+</details>
+{{- else}}
+<div class="leaf">{{template "head" .}}{{template "body" .}}</div>
+{{- end}}
+{{- end}}
+`))
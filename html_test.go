@@ -0,0 +1,15 @@
+package okrs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteHTMLTree(&buf, writerTestTree())
+	require.NoError(t, err)
+	checkGolden(t, "tree.html", buf.Bytes())
+}
@@ -0,0 +1,525 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Jira loads OKRs from issues of a Jira instance.
+//
+// Parents are taken from the parent field (sub-tasks and children of epics), from the legacy
+// "Epic Link" field, if configured, and from issue links with the "is child of" relation.
+// Parents that don't match the query are fetched separately. If a parent is removed or not visible
+// to the user, its children stay at the top level.
+//
+// Issues are listed with the enhanced JQL search of Jira Cloud, or with the v2 search of Jira Data Center,
+// which was removed from Jira Cloud.
+type Jira struct {
+	// URL is the address of the Jira instance, for example https://example.atlassian.net.
+	URL string `json:"url" yaml:"url"`
+	// User is the account email for Jira Cloud. If set, the token is sent with basic auth,
+	// otherwise it is sent as a bearer token (personal access token of Jira Data Center).
+	User  string `json:"user,omitempty" yaml:"user,omitempty"`
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// TokenEnv is the name of the environment variable with the API token.
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env,omitempty"`
+	// Projects selects all issues of projects with given keys.
+	Projects []string `json:"projects,omitempty" yaml:"projects,omitempty"`
+	// JQL selects issues with a query. It is combined with Projects, if both are set.
+	JQL string `json:"jql,omitempty" yaml:"jql,omitempty"`
+	// API selects the search API: "v3" for the enhanced search of Jira Cloud, or "v2" for Jira Data Center.
+	// By default, the enhanced search is used if the instance supports it, and v2 search otherwise.
+	API string `json:"api,omitempty" yaml:"api,omitempty"`
+	// EpicLinkField is the ID of the "Epic Link" custom field, for example "customfield_10014".
+	// It is only required for instances which don't set the parent field of epic children.
+	EpicLinkField string `json:"epic_link_field,omitempty" yaml:"epic_link_field,omitempty"`
+	// Priorities maps names of Jira priorities to OKR priorities. It extends the default mapping.
+	Priorities map[string]int `json:"priorities,omitempty" yaml:"priorities,omitempty"`
+	// Canceled is a list of statuses and resolutions that mark an issue as canceled.
+	Canceled []string `json:"canceled,omitempty" yaml:"canceled,omitempty"`
+	// Cache is a directory for cached responses. Cache options are the same as for Github.
+	Cache    string `json:"cache,omitempty" yaml:"cache,omitempty"`
+	CacheTTL string `json:"cache_ttl,omitempty" yaml:"cache_ttl,omitempty"`
+	// Refresh ignores cache TTL and fetches all issues again.
+	Refresh bool `json:"-" yaml:"-"`
+	// Offline only uses cached responses, regardless of their age.
+	Offline bool `json:"-" yaml:"-"`
+
+	hcli *http.Client
+}
+
+// jiraPriorities maps default priority schemes of Jira to OKR priorities.
+var jiraPriorities = map[string]int{
+	"highest": 0, "high": 1, "medium": 2, "low": 3, "lowest": 4,
+	"blocker": 0, "critical": 1, "major": 2, "minor": 3, "trivial": 4,
+}
+
+var jiraCanceled = []string{"Won't Do", "Won't Fix", "Canceled", "Cancelled", "Declined"}
+
+// jiraChildLink is a name of the issue link relation between a child and its parent.
+const jiraChildLink = "is child of"
+
+const (
+	jiraAPIv2 = "v2" // search of Jira Data Center
+	jiraAPIv3 = "v3" // enhanced JQL search of Jira Cloud
+)
+
+type jiraRef struct {
+	Key string `json:"key"`
+}
+
+type jiraUser struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	DisplayName  string `json:"displayName"`
+}
+
+type jiraIssueLink struct {
+	Type struct {
+		Inward  string `json:"inward"`
+		Outward string `json:"outward"`
+	} `json:"type"`
+	InwardIssue  *jiraRef `json:"inwardIssue"`
+	OutwardIssue *jiraRef `json:"outwardIssue"`
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary string `json:"summary"`
+		Project struct {
+			Key  string `json:"key"`
+			Name string `json:"name"`
+		} `json:"project"`
+		Status struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Resolution *struct {
+			Name string `json:"name"`
+		} `json:"resolution"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Assignee    *jiraUser `json:"assignee"`
+		DueDate     string    `json:"duedate"`
+		FixVersions []struct {
+			Name        string `json:"name"`
+			ReleaseDate string `json:"releaseDate"`
+		} `json:"fixVersions"`
+		Parent     *jiraRef        `json:"parent"`
+		IssueLinks []jiraIssueLink `json:"issuelinks"`
+	} `json:"fields"`
+	// EpicLink is a key of the epic, copied from the custom field.
+	EpicLink string `json:"epic_link,omitempty"`
+}
+
+func (j *Jira) baseURL() string {
+	return strings.TrimSuffix(j.URL, "/")
+}
+
+func (j *Jira) cache() *fileCache {
+	ttl, _ := parseCacheTTL(j.CacheTTL)
+	return &fileCache{dir: j.Cache, prefix: "jira_", ttl: ttl, refresh: j.Refresh, offline: j.Offline}
+}
+
+// checkConfig validates the config.
+func (j *Jira) checkConfig() error {
+	if j.URL == "" {
+		return fmt.Errorf("jira: url is not set")
+	} else if err := checkURL("jira url", j.URL); err != nil {
+		return err
+	}
+	if len(j.Projects) == 0 && j.JQL == "" {
+		return fmt.Errorf("jira: either projects or jql must be set")
+	}
+	switch j.API {
+	case "", jiraAPIv2, jiraAPIv3:
+	default:
+		return fmt.Errorf("jira: unsupported api %q, expected %s or %s", j.API, jiraAPIv2, jiraAPIv3)
+	}
+	if j.Refresh && j.Offline {
+		return fmt.Errorf("cannot refresh the cache in offline mode")
+	}
+	_, err := parseCacheTTL(j.CacheTTL)
+	return err
+}
+
+// client returns a client of Jira REST API that authorizes requests with a given token.
+func (j *Jira) client(tok string) *restClient {
+	return &restClient{
+		name: "jira", base: j.baseURL() + "/rest/api", hcli: j.hcli,
+		auth: func(req *http.Request) {
+			if j.User != "" {
+				req.SetBasicAuth(j.User, tok)
+			} else if tok != "" {
+				req.Header.Set("Authorization", "Bearer "+tok)
+			}
+		},
+	}
+}
+
+// query returns JQL that selects all configured issues.
+func (j *Jira) query() string {
+	var keys []string
+	for _, p := range j.Projects {
+		keys = append(keys, strconv.Quote(p))
+	}
+	q := j.JQL
+	if len(keys) != 0 {
+		proj := "project in (" + strings.Join(keys, ", ") + ")"
+		if q == "" {
+			q = proj
+		} else {
+			q = proj + " AND (" + q + ")"
+		}
+	}
+	return q
+}
+
+// fields returns the list of fields to request.
+func (j *Jira) fields() string {
+	fields := "summary,project,status,resolution,priority,assignee,duedate,fixVersions,parent,issuelinks"
+	if j.EpicLinkField != "" {
+		fields += "," + j.EpicLinkField
+	}
+	return fields
+}
+
+// decodeIssues decodes raw issues and copies the value of the epic link field.
+func (j *Jira) decodeIssues(raw []json.RawMessage) ([]*jiraIssue, error) {
+	out := make([]*jiraIssue, 0, len(raw))
+	for _, data := range raw {
+		var is jiraIssue
+		if err := json.Unmarshal(data, &is); err != nil {
+			return nil, err
+		}
+		if j.EpicLinkField != "" {
+			var custom struct {
+				Fields map[string]interface{} `json:"fields"`
+			}
+			if err := json.Unmarshal(data, &custom); err != nil {
+				return nil, err
+			}
+			is.EpicLink, _ = custom.Fields[j.EpicLinkField].(string)
+		}
+		out = append(out, &is)
+	}
+	return out, nil
+}
+
+// search requests all issues matching the query and caches them as a single list.
+func (j *Jira) search(ctx context.Context, cli *restClient, jql string) ([]*jiraIssue, error) {
+	h := fnv.New64a()
+	h.Write([]byte(jql + "\x00" + j.fields()))
+	key := fmt.Sprintf("search_%x", h.Sum64())
+	c := j.cache()
+	var out []*jiraIssue
+	if c.get(key, &out) {
+		return out, nil
+	} else if err := c.checkOffline(key); err != nil {
+		return nil, err
+	}
+	var err error
+	switch j.API {
+	case jiraAPIv2:
+		out, err = j.searchV2(ctx, cli, jql)
+	case jiraAPIv3:
+		out, err = j.searchJQL(ctx, cli, jql)
+	default:
+		out, err = j.searchJQL(ctx, cli, jql)
+		if isNotFound(err) {
+			// enhanced search is only available in Jira Cloud
+			out, err = j.searchV2(ctx, cli, jql)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = []*jiraIssue{}
+	}
+	c.put(key, out, nil)
+	return out, nil
+}
+
+// searchJQL requests all issues with the enhanced JQL search of Jira Cloud, which is paginated with tokens.
+// Descriptions are returned in Atlassian Document Format by this API, but they are not requested.
+func (j *Jira) searchJQL(ctx context.Context, cli *restClient, jql string) ([]*jiraIssue, error) {
+	var out []*jiraIssue
+	for next := ""; ; {
+		q := url.Values{
+			"jql": {jql}, "fields": {j.fields()},
+			"maxResults": {"100"},
+		}
+		if next != "" {
+			q.Set("nextPageToken", next)
+		}
+		var page struct {
+			Issues        []json.RawMessage `json:"issues"`
+			NextPageToken string            `json:"nextPageToken"`
+			IsLast        bool              `json:"isLast"`
+		}
+		if err := cli.get(ctx, "3/search/jql?"+q.Encode(), &page); err != nil {
+			return nil, err
+		}
+		issues, err := j.decodeIssues(page.Issues)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, issues...)
+		if page.IsLast || page.NextPageToken == "" || len(issues) == 0 {
+			break
+		}
+		next = page.NextPageToken
+	}
+	return out, nil
+}
+
+// searchV2 requests all issues with the search of Jira Data Center, which is paginated with offsets.
+func (j *Jira) searchV2(ctx context.Context, cli *restClient, jql string) ([]*jiraIssue, error) {
+	var out []*jiraIssue
+	for start := 0; ; {
+		q := url.Values{
+			"jql": {jql}, "fields": {j.fields()},
+			"startAt": {strconv.Itoa(start)}, "maxResults": {"100"},
+		}
+		var page struct {
+			Total  int               `json:"total"`
+			Issues []json.RawMessage `json:"issues"`
+		}
+		if err := cli.get(ctx, "2/search?"+q.Encode(), &page); err != nil {
+			return nil, err
+		}
+		issues, err := j.decodeIssues(page.Issues)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, issues...)
+		start += len(issues)
+		if len(issues) == 0 || start >= page.Total {
+			break
+		}
+	}
+	return out, nil
+}
+
+// getIssue requests a single issue and caches it.
+func (j *Jira) getIssue(ctx context.Context, cli *restClient, key string) (*jiraIssue, error) {
+	ckey := "issue_" + key
+	c := j.cache()
+	var is jiraIssue
+	if c.get(ckey, &is) {
+		return &is, nil
+	} else if err := c.checkOffline(ckey); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := cli.get(ctx, "2/issue/"+url.PathEscape(key)+"?fields="+url.QueryEscape(j.fields()), &raw); err != nil {
+		return nil, err
+	}
+	issues, err := j.decodeIssues([]json.RawMessage{raw})
+	if err != nil {
+		return nil, err
+	}
+	c.put(ckey, issues[0], nil)
+	return issues[0], nil
+}
+
+// parentKey returns the key of the parent issue, if any.
+func (is *jiraIssue) parentKey() string {
+	if p := is.Fields.Parent; p != nil && p.Key != "" {
+		return p.Key
+	} else if is.EpicLink != "" {
+		return is.EpicLink
+	}
+	for _, l := range is.Fields.IssueLinks {
+		// a link is read as "<this issue> <relation> <linked issue>"
+		if l.OutwardIssue != nil && strings.EqualFold(l.Type.Outward, jiraChildLink) {
+			return l.OutwardIssue.Key
+		} else if l.InwardIssue != nil && strings.EqualFold(l.Type.Inward, jiraChildLink) {
+			return l.InwardIssue.Key
+		}
+	}
+	return ""
+}
+
+func (j *Jira) priority(name string) *int {
+	for k, v := range j.Priorities {
+		if strings.EqualFold(k, name) {
+			return &v
+		}
+	}
+	if v, ok := jiraPriorities[strings.ToLower(name)]; ok {
+		return &v
+	}
+	// priority schemes like "P1"
+	if _, pri := issueTitle("[" + name + "]"); pri != nil {
+		return pri
+	}
+	return nil
+}
+
+func (j *Jira) isCanceled(is *jiraIssue) bool {
+	canceled := j.Canceled
+	if len(canceled) == 0 {
+		canceled = jiraCanceled
+	}
+	for _, s := range canceled {
+		if strings.EqualFold(s, is.Fields.Status.Name) {
+			return true
+		} else if r := is.Fields.Resolution; r != nil && strings.EqualFold(s, r.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Jira) issueURL(key string) string {
+	return j.baseURL() + "/browse/" + key
+}
+
+func (j *Jira) asNode(tr *Tree, is *jiraIssue) *Node {
+	f := &is.Fields
+	u := j.issueURL(is.Key)
+	title, pri := issueTitle(f.Summary)
+	nd := tr.NewNode(Node{
+		ID: u, Title: title,
+		Link:     Link{Title: is.Key, URL: u},
+		Priority: pri,
+	})
+	if nd.Priority == nil && f.Priority != nil {
+		nd.Priority = j.priority(f.Priority.Name)
+	}
+	if a := f.Assignee; a != nil {
+		name := a.Name
+		if name == "" {
+			name = a.EmailAddress
+		}
+		if name == "" {
+			name = a.DisplayName
+		}
+		if name != "" {
+			nd.Owners = appendOwners(nd.Owners, name)
+		}
+	}
+	for _, v := range f.FixVersions {
+		if p := parsePeriod(v.Name); p != "" {
+			nd.Period = p
+			nd.Due, _ = parseDate(v.ReleaseDate)
+			break
+		}
+	}
+	if f.DueDate != "" {
+		nd.Due, _ = parseDate(f.DueDate)
+	}
+	switch {
+	case j.isCanceled(is):
+		nd.Status = StatusCanceled
+	case f.Status.StatusCategory.Key == "done":
+		nd.Status = StatusDone
+		markDone(nd)
+	}
+	return nd
+}
+
+// LoadTree reads all issues matching the query, as well as their parents.
+func (j *Jira) LoadTree(ctx context.Context, tr *Tree) error {
+	if err := j.checkConfig(); err != nil {
+		return err
+	}
+	tok, err := configToken(j.Token, j.TokenEnv)
+	if err != nil {
+		return err
+	}
+	cli := j.client(tok)
+	issues, err := j.search(ctx, cli, j.query())
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]*jiraIssue, len(issues))
+	for _, is := range issues {
+		byKey[is.Key] = is
+	}
+	// parents may not match the query - fetch them separately
+	missing := make(map[string]struct{})
+	for i := 0; i < len(issues); i++ {
+		key := issues[i].parentKey()
+		if _, ok := missing[key]; ok || key == "" || byKey[key] != nil {
+			continue
+		}
+		is, err := j.getIssue(ctx, cli, key)
+		if isNotFound(err) || isForbidden(err) {
+			// the parent is removed or not visible to the user - keep its children at the top level
+			log.Printf("cannot get parent of %s: %v", issues[i].Key, err)
+			missing[key] = struct{}{}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("cannot get parent of %s: %v", issues[i].Key, err)
+		}
+		byKey[key] = is
+		issues = append(issues, is)
+	}
+	return j.asTree(tr, issues)
+}
+
+func (j *Jira) asTree(tr *Tree, issues []*jiraIssue) error {
+	nodes := make(map[string]*Node, len(issues))
+	for _, is := range issues {
+		nodes[is.Key] = j.asNode(tr, is)
+	}
+	projects := make(map[string]*Node)
+	for _, is := range issues {
+		nd := nodes[is.Key]
+		if par := nodes[is.parentKey()]; par != nil {
+			if err := par.AddChild(nd); err == nil {
+				nd.parent = &par.Link
+				continue
+			} else if err = tr.loadError(RuleCycle, nd, fmt.Errorf("invalid parent link in %s: %v", is.Key, err)); err != nil {
+				return err
+			}
+			// keep the issue at the top level
+		}
+		// top-level issues are grouped by project
+		p := &is.Fields.Project
+		proj := projects[p.Key]
+		if proj == nil {
+			title := p.Name
+			if title == "" {
+				title = p.Key
+			}
+			proj = tr.NewNode(Node{
+				Title: title,
+				Link:  Link{Title: p.Key, URL: j.issueURL(p.Key)},
+			})
+			projects[p.Key] = proj
+		}
+		if err := proj.AddChild(nd); err != nil {
+			return err
+		}
+	}
+	for _, nd := range nodes {
+		markOpen(nd)
+		nd.Sort()
+	}
+	keys := make([]string, 0, len(projects))
+	for k := range projects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	conts := make([]*Node, 0, len(keys))
+	for _, k := range keys {
+		conts = append(conts, projects[k])
+	}
+	return addContainers(tr, conts)
+}
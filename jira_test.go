@@ -0,0 +1,255 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func jiraIssueJSON(key, summary, status string, fields map[string]interface{}) map[string]interface{} {
+	proj := key[:len(key)-2]
+	f := map[string]interface{}{
+		"summary": summary,
+		"project": map[string]string{"key": proj, "name": proj + " project"},
+		"status":  map[string]interface{}{"name": status, "statusCategory": map[string]string{"key": "new"}},
+	}
+	if status == "Done" || status == "Closed" {
+		f["status"].(map[string]interface{})["statusCategory"] = map[string]string{"key": "done"}
+	}
+	for k, v := range fields {
+		f[k] = v
+	}
+	return map[string]interface{}{"key": key, "fields": f}
+}
+
+func TestJira(t *testing.T) {
+	pages := [][]map[string]interface{}{
+		{
+			jiraIssueJSON("OKR-1", "Grow revenue", "In Progress", map[string]interface{}{
+				"priority":    map[string]string{"name": "Highest"},
+				"fixVersions": []map[string]string{{"name": "2026 Q4", "releaseDate": "2026-12-31"}},
+			}),
+			jiraIssueJSON("OKR-2", "Close deals", "To Do", map[string]interface{}{
+				"parent":   map[string]string{"key": "OKR-1"},
+				"priority": map[string]string{"name": "Minor"},
+				"assignee": map[string]string{"name": "alice", "displayName": "Alice"},
+			}),
+			jiraIssueJSON("OKR-3", "Hire", "Done", map[string]interface{}{
+				"parent":  map[string]string{"key": "OKR-2"},
+				"duedate": "2026-11-01",
+			}),
+		},
+		{
+			jiraIssueJSON("SALES-1", "[P2] Onboard", "To Do", map[string]interface{}{
+				"assignee": map[string]string{"displayName": "Bob"},
+				"issuelinks": []map[string]interface{}{
+					{
+						"type":         map[string]string{"inward": "blocks", "outward": "is blocked by"},
+						"outwardIssue": map[string]string{"key": "OKR-3"},
+					},
+					{
+						"type":         map[string]string{"inward": "is parent of", "outward": "is child of"},
+						"outwardIssue": map[string]string{"key": "OKR-1"},
+					},
+				},
+			}),
+			jiraIssueJSON("SALES-2", "Legacy", "Closed", map[string]interface{}{
+				"resolution": map[string]string{"name": "Won't Do"},
+			}),
+			jiraIssueJSON("SALES-3", "Expand", "To Do", map[string]interface{}{
+				"customfield_10014": "EXT-9",
+			}),
+		},
+	}
+	ext := jiraIssueJSON("EXT-9", "External epic", "To Do", nil)
+
+	cases := []struct {
+		name     string
+		api      string
+		enhanced bool // enhanced search is supported, while v2 search is removed
+		requests int
+	}{
+		{name: "cloud", enhanced: true, requests: 3}, // two pages and a parent
+		{name: "data center", requests: 4},           // falls back to v2 search
+		{name: "v2", api: jiraAPIv2, requests: 3},
+		{name: "v3", api: jiraAPIv3, enhanced: true, requests: 3},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if user, pass, _ := r.BasicAuth(); user != "bot@example.com" || pass != "secret" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				if jql := r.URL.Query().Get("jql"); r.URL.Path != "/rest/api/2/issue/EXT-9" &&
+					jql != `project in ("OKR", "SALES") AND (type != Task)` {
+					http.Error(w, "unexpected query: "+jql, http.StatusBadRequest)
+					return
+				}
+				switch r.URL.Path {
+				case "/rest/api/3/search/jql":
+					if !c.enhanced {
+						http.NotFound(w, r)
+						return
+					}
+					page := map[string]interface{}{"issues": pages[0], "nextPageToken": "page2"}
+					if r.URL.Query().Get("nextPageToken") == "page2" {
+						page = map[string]interface{}{"issues": pages[1], "isLast": true}
+					}
+					json.NewEncoder(w).Encode(page)
+				case "/rest/api/2/search":
+					if c.enhanced {
+						http.Error(w, "the api is removed", http.StatusGone)
+						return
+					}
+					start, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+					var issues []map[string]interface{}
+					for i, n := 0, 0; i < len(pages); n += len(pages[i]) {
+						if n == start {
+							issues = pages[i]
+							break
+						}
+						i++
+					}
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"startAt": start, "total": len(pages[0]) + len(pages[1]), "issues": issues,
+					})
+				case "/rest/api/2/issue/EXT-9":
+					json.NewEncoder(w).Encode(ext)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			t.Setenv("OKRS_JIRA_TOKEN", "secret")
+			j := &Jira{
+				URL: srv.URL + "/", User: "bot@example.com", TokenEnv: "OKRS_JIRA_TOKEN",
+				Projects: []string{"OKR", "SALES"}, JQL: "type != Task", API: c.api,
+				EpicLinkField: "customfield_10014",
+				Cache:         t.TempDir(),
+			}
+			load := func() *Node {
+				tr := NewTree()
+				err := j.LoadTree(context.Background(), tr)
+				require.NoError(t, err)
+				return tr.Root()
+			}
+			root := load()
+			require.Equal(t, c.requests, requests)
+
+			require.Len(t, root.Sub, 3)
+			ext9, okr, sales := root.Sub[0], root.Sub[1], root.Sub[2]
+			require.Equal(t, Link{Title: "EXT", URL: srv.URL + "/browse/EXT"}, ext9.Link)
+			require.Equal(t, "OKR project", okr.Title)
+
+			require.Len(t, okr.Sub, 1)
+			obj := okr.Sub[0]
+			require.Equal(t, "Grow revenue", obj.Title)
+			require.Equal(t, Link{Title: "OKR-1", URL: srv.URL + "/browse/OKR-1"}, obj.Link)
+			require.Equal(t, pri(0), obj.Priority)
+			require.Equal(t, "2026-Q4", obj.Period)
+			require.Equal(t, date("2026-12-31"), obj.Due)
+			require.Len(t, obj.Sub, 2)
+
+			var deals, onboard *Node
+			for _, n := range obj.Sub {
+				switch n.Title {
+				case "Close deals":
+					deals = n
+				case "Onboard":
+					onboard = n
+				}
+			}
+			require.NotNil(t, deals)
+			require.Equal(t, pri(3), deals.Priority)
+			require.Equal(t, []string{"alice"}, deals.Owners)
+			require.Len(t, deals.Sub, 1)
+			hire := deals.Sub[0]
+			require.Equal(t, StatusDone, hire.Status)
+			require.Equal(t, &Progress{Done: 1, Total: 1}, hire.Progress)
+			require.Equal(t, date("2026-11-01"), hire.Due)
+
+			require.NotNil(t, onboard)
+			require.Equal(t, pri(2), onboard.Priority)
+			require.Equal(t, []string{"Bob"}, onboard.Owners)
+			require.Equal(t, &Progress{Done: 0, Total: 1}, onboard.Progress)
+
+			require.Len(t, sales.Sub, 1)
+			require.Equal(t, "Legacy", sales.Sub[0].Title)
+			require.Equal(t, StatusCanceled, sales.Sub[0].Status)
+			require.Nil(t, sales.Sub[0].Progress)
+
+			require.Len(t, ext9.Sub, 1)
+			require.Equal(t, "External epic", ext9.Sub[0].Title)
+			require.Equal(t, "Expand", ext9.Sub[0].Sub[0].Title)
+
+			// the second load is served from the cache
+			j.Offline = true
+			cached := load()
+			require.Equal(t, c.requests, requests)
+			require.Equal(t, root.Sub[1].Sub[0].Sub[0].Title, cached.Sub[1].Sub[0].Sub[0].Title)
+			require.Equal(t, "Expand", cached.Sub[0].Sub[0].Sub[0].Title)
+
+			j.Offline, j.Cache = false, ""
+			j.TokenEnv = "OKRS_NO_TOKEN"
+			require.Error(t, j.LoadTree(context.Background(), NewTree()))
+			j.TokenEnv, j.User = "OKRS_JIRA_TOKEN", ""
+			require.Error(t, j.LoadTree(context.Background(), NewTree()))
+			j.API = "v4"
+			require.Error(t, j.checkConfig())
+		})
+	}
+}
+
+func TestJiraMissingParent(t *testing.T) {
+	hidden := http.StatusForbidden
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/search":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"startAt": 0, "total": 3, "issues": []map[string]interface{}{
+					jiraIssueJSON("OKR-1", "Removed parent", "To Do", map[string]interface{}{
+						"parent": map[string]string{"key": "OLD-1"},
+					}),
+					jiraIssueJSON("OKR-2", "Same parent", "To Do", map[string]interface{}{
+						"parent": map[string]string{"key": "OLD-1"},
+					}),
+					jiraIssueJSON("OKR-3", "Hidden parent", "To Do", map[string]interface{}{
+						"parent": map[string]string{"key": "HR-1"},
+					}),
+				},
+			})
+		case "/rest/api/2/issue/HR-1":
+			http.Error(w, http.StatusText(hidden), hidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	load := func() (*Tree, error) {
+		j := &Jira{URL: srv.URL, Token: "secret", Projects: []string{"OKR"}, API: jiraAPIv2, Cache: t.TempDir()}
+		tr := NewTree()
+		return tr, j.LoadTree(context.Background(), tr)
+	}
+	// issues with removed or inaccessible parents stay at the top level
+	tr, err := load()
+	require.NoError(t, err)
+	root := tr.Root()
+	require.Equal(t, "OKR project", root.Title)
+	require.Len(t, root.Sub, 3)
+
+	// other errors are not ignored
+	hidden = http.StatusInternalServerError
+	_, err = load()
+	require.Error(t, err)
+}
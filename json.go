@@ -11,7 +11,18 @@ func init() {
 		Write: func(w io.Writer, t *Node) error {
 			enc := json.NewEncoder(w)
 			enc.SetIndent("", "\t")
-			return enc.Encode(t)
+			return enc.Encode(acyclic(t))
+		},
+	})
+	RegisterTreeReader(TreeReaderDesc{
+		Name: "json", Ext: "json",
+		Read: func(r io.Reader, tr *Tree) error {
+			var nd Node
+			if err := json.NewDecoder(r).Decode(&nd); err != nil {
+				return err
+			}
+			tr.addRoot(&nd)
+			return nil
 		},
 	})
 }
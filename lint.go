@@ -0,0 +1,234 @@
+package okrs
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   = Severity("error")
+	SeverityWarning = Severity("warning")
+)
+
+// Lint rules.
+const (
+	RuleNoKeyResults     = "no-key-results"
+	RuleMaxDepth         = "max-depth"
+	RuleDuplicateTitle   = "duplicate-title"
+	RuleMissingOwner     = "missing-owner"
+	RuleProgress         = "invalid-progress"
+	RuleUnresolvedParent = "unresolved-parent"
+	RuleSelfReference    = "self-reference"
+	RuleCycle            = "cycle"
+	// RuleConflictingParent is reported by loaders when an issue is linked to different parents.
+	RuleConflictingParent = "conflicting-parent"
+)
+
+// Problem is a single issue found in the OKR tree by Lint.
+type Problem struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Path     []string `json:"path,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s: [%s]", p.Severity, p.Rule)
+	if len(p.Path) != 0 {
+		s += " " + strings.Join(p.Path, " / ") + ":"
+	}
+	s += " " + p.Message
+	if p.URL != "" {
+		s += " (" + p.URL + ")"
+	}
+	return s
+}
+
+// Lint configures structural checks of the OKR tree.
+type Lint struct {
+	// MaxDepth is the maximal depth of key results in the tree. Zero means no limit.
+	MaxDepth int `json:"max_depth,omitempty" yaml:"max_depth,omitempty"`
+	// RequireOwners reports objectives without owners as errors instead of warnings.
+	RequireOwners bool `json:"require_owners,omitempty" yaml:"require_owners,omitempty"`
+	// Disable is a list of rules to skip.
+	Disable []string `json:"disable,omitempty" yaml:"disable,omitempty"`
+}
+
+func (l Lint) enabled(rule string) bool {
+	for _, r := range l.Disable {
+		if r == rule {
+			return false
+		}
+	}
+	return true
+}
+
+// HasErrors checks if any of the problems has an error severity.
+func HasErrors(arr []Problem) bool {
+	return CountSeverity(arr, SeverityError) != 0
+}
+
+// CountSeverity returns the number of problems with a given severity.
+func CountSeverity(arr []Problem, sev Severity) int {
+	n := 0
+	for _, p := range arr {
+		if p.Severity == sev {
+			n++
+		}
+	}
+	return n
+}
+
+// loadError reports a structural error of the node found by a loader. In lenient mode, the error is recorded
+// as a problem and nil is returned, so the loader can skip the link and continue.
+func (tr *Tree) loadError(rule string, n *Node, err error) error {
+	if !tr.lenient {
+		return err
+	}
+	tr.problems = append(tr.problems, Problem{
+		Rule: rule, Severity: SeverityError,
+		URL: n.Link.URL, Message: err.Error(),
+	})
+	return nil
+}
+
+// nodeName returns a short name of the node suitable for error messages.
+func nodeName(n *Node) string {
+	switch {
+	case n.Title != "" && n.Link.Title != "":
+		return n.Link.Title + " " + n.Title
+	case n.Title != "":
+		return n.Title
+	case n.Link.Title != "":
+		return n.Link.Title
+	case n.Link.URL != "":
+		return n.Link.URL
+	}
+	return "<untitled>"
+}
+
+// Check runs all enabled rules on the tree and returns a list of problems, including problems recorded
+// by loaders of a lenient tree. It is safe to run on trees with cycles.
+func (l Lint) Check(tr *Tree) []Problem {
+	var out []Problem
+	if tr != nil {
+		for _, p := range tr.problems {
+			if l.enabled(p.Rule) {
+				out = append(out, p)
+			}
+		}
+	}
+	report := func(rule string, sev Severity, path []*Node, n *Node, format string, args ...interface{}) {
+		if !l.enabled(rule) {
+			return
+		}
+		p := Problem{
+			Rule: rule, Severity: sev,
+			URL:     n.Link.URL,
+			Message: fmt.Sprintf(format, args...),
+		}
+		for _, pn := range path {
+			if pn.isProxyNode() {
+				continue
+			}
+			p.Path = append(p.Path, nodeName(pn))
+		}
+		out = append(out, p)
+	}
+	root := tr.Root()
+	if root == nil {
+		return nil
+	}
+
+	// index all links to resolve parent references
+	links := make(map[string]struct{})
+	seen := make(map[*Node]struct{})
+	var index func(n *Node)
+	index = func(n *Node) {
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		if n.Link.Title != "" {
+			links[n.Link.Title] = struct{}{}
+		}
+		if n.Link.URL != "" {
+			links[n.Link.URL] = struct{}{}
+		}
+		for _, s := range n.Sub {
+			index(s)
+		}
+	}
+	index(root)
+
+	titles := make(map[string]*Node)
+	visited := make(map[*Node]struct{})
+	onPath := make(map[*Node]struct{})
+	var check func(path []*Node, n *Node)
+	check = func(path []*Node, n *Node) {
+		path = append(path, n)
+		depth := len(path) - 1
+		if _, ok := visited[n]; ok {
+			return
+		}
+		visited[n] = struct{}{}
+		onPath[n] = struct{}{}
+		defer delete(onPath, n)
+
+		if l.MaxDepth > 0 && depth == l.MaxDepth+1 {
+			report(RuleMaxDepth, SeverityError, path, n, "node is deeper than %d levels", l.MaxDepth)
+		}
+		if p := n.Progress; p != nil && (p.Done > p.Total || p.Done < 0 || p.Total < 0) {
+			report(RuleProgress, SeverityError, path, n, "invalid progress: %d/%d", p.Done, p.Total)
+		}
+		if p := n.parent; p != nil {
+			_, ok1 := links[p.Title]
+			_, ok2 := links[p.URL]
+			if !ok1 && !ok2 {
+				ref := p.Title
+				if ref == "" {
+					ref = p.URL
+				}
+				report(RuleUnresolvedParent, SeverityError, path, n, "cannot find parent %s", ref)
+			}
+		}
+		objective := depth == 1 && !n.isProxyNode()
+		if n.Kind != "" {
+			objective = n.Kind == KindObjective
+		}
+		if objective {
+			if len(n.Sub) == 0 && n.Metric == nil {
+				report(RuleNoKeyResults, SeverityWarning, path, n, "objective has no key results")
+			}
+			if len(n.Owners) == 0 && n.Team == "" {
+				sev := SeverityWarning
+				if l.RequireOwners {
+					sev = SeverityError
+				}
+				report(RuleMissingOwner, sev, path, n, "objective has no owners")
+			}
+		}
+		if n.Title != "" {
+			if n2, ok := titles[n.Title]; ok && n2 != n {
+				report(RuleDuplicateTitle, SeverityWarning, path, n, "duplicate title %q", n.Title)
+			} else {
+				titles[n.Title] = n
+			}
+		}
+		for _, s := range n.Sub {
+			if s == n {
+				report(RuleSelfReference, SeverityError, path, n, "node references itself as a child")
+				continue
+			} else if _, ok := onPath[s]; ok {
+				report(RuleCycle, SeverityError, path, n, "cycle: %s is an ancestor of %s", nodeName(s), nodeName(n))
+				continue
+			}
+			check(path, s)
+		}
+	}
+	check(nil, root)
+	return out
+}
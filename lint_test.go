@@ -0,0 +1,57 @@
+package okrs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	self := &Node{Title: "self", Owners: []string{"alice"}}
+	self.Sub = []*Node{self}
+	a := &Node{Title: "a"}
+	b := &Node{Title: "b", Sub: []*Node{a}}
+	a.Sub = []*Node{b}
+
+	tr := &Tree{root: &Node{
+		Title: "Root",
+		Sub: []*Node{
+			{Title: "empty", Owners: []string{"bob"}},
+			{Title: "deep", Team: "infra", Sub: []*Node{
+				{Title: "kr", Progress: &Progress{Done: 3, Total: 2}, Sub: []*Node{
+					{Title: "kr"},
+				}},
+			}},
+			{Title: "orphan", Owners: []string{"carol"}, parent: &Link{Title: "#5", URL: "#5"}, Sub: []*Node{
+				{Title: "kr 2"},
+			}},
+			self,
+			{Title: "cycle", Sub: []*Node{a}},
+			{Title: "shared kr", Kind: KindKeyResult},
+			{Title: "program", Team: "infra", Sub: []*Node{
+				{Title: "nested", Kind: KindObjective},
+			}},
+		},
+	}}
+	problems := Lint{MaxDepth: 2}.Check(tr)
+	var rules []string
+	for _, p := range problems {
+		rules = append(rules, p.String())
+	}
+	require.Equal(t, []string{
+		"warning: [no-key-results] Root / empty: objective has no key results",
+		"error: [invalid-progress] Root / deep / kr: invalid progress: 3/2",
+		"error: [max-depth] Root / deep / kr / kr: node is deeper than 2 levels",
+		"warning: [duplicate-title] Root / deep / kr / kr: duplicate title \"kr\"",
+		"error: [unresolved-parent] Root / orphan: cannot find parent #5",
+		"error: [self-reference] Root / self: node references itself as a child",
+		"warning: [missing-owner] Root / cycle: objective has no owners",
+		"error: [max-depth] Root / cycle / a / b: node is deeper than 2 levels",
+		"error: [cycle] Root / cycle / a / b: cycle: a is an ancestor of b",
+		"warning: [no-key-results] Root / program / nested: objective has no key results",
+		"warning: [missing-owner] Root / program / nested: objective has no owners",
+	}, rules)
+	require.True(t, HasErrors(problems))
+	require.Equal(t, 6, CountSeverity(problems, SeverityError))
+	require.Equal(t, 5, CountSeverity(problems, SeverityWarning))
+}
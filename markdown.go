@@ -19,6 +19,10 @@ func init() {
 		Name: "md", Ext: "md",
 		Write: WriteMDTree,
 	})
+	RegisterTreeReader(TreeReaderDesc{
+		Name: "md", Ext: "md",
+		Read: ParseMDTree,
+	})
 }
 
 func ParseMDTree(r io.Reader, tr *Tree) error {
@@ -72,7 +76,11 @@ var (
 	rePerc     = regexp.MustCompile(`([\d]+)%`)
 	reParts    = regexp.MustCompile(`([\d]+)/([\d]+)`)
 	reHashRef  = regexp.MustCompile(`#(\d+)`)
+	reCrossRef = regexp.MustCompile(`(?:^|\s)([\w.-]+(?:/[\w.-]+)+#\d+)\b`)
+	reEpicRef  = regexp.MustCompile(`(?:^|\s)((?:[\w.-]+(?:/[\w.-]+)*)?&\d+)\b`) // GitLab epics
 	reURL      = regexp.MustCompile(`\(?(?:\[[^]]+\]\()?(http(?:s)?://[^)\s]+)\)?\)?`)
+	reMention  = regexp.MustCompile(`(?:^|\s+)@([\w-]+(?:/[\w-]+)?)\s*$`)
+	reValue    = regexp.MustCompile(`^([-+]?[\d,]*\.?\d+)\s*(.*)$`)
 )
 
 func printMD(w io.Writer, n *blackfriday.Node, tabs string) {
@@ -96,7 +104,7 @@ func printMD(w io.Writer, n *blackfriday.Node, tabs string) {
 }
 
 func mdDoc2Tree(tr *Tree, doc *blackfriday.Node) {
-	root := tr.root
+	root := tr.NewNode(Node{})
 	cur := func() (*Node, int) {
 		n, lvl := root, 0
 		for len(n.Sub) != 0 {
@@ -109,7 +117,8 @@ func mdDoc2Tree(tr *Tree, doc *blackfriday.Node) {
 		n, lvl := root, 0
 		for lvl < dst {
 			if len(n.Sub) == 0 {
-				n.AddChild(tr.NewNode(Node{}))
+				// new nodes have no children, thus cannot form a cycle
+				_ = n.AddChild(tr.NewNode(Node{}))
 			}
 			n = n.Sub[len(n.Sub)-1]
 			lvl++
@@ -123,19 +132,19 @@ func mdDoc2Tree(tr *Tree, doc *blackfriday.Node) {
 			var nd Node
 			if txt := n.FirstChild; txt != nil && txt.Type == blackfriday.Text {
 				nd.Title = strings.TrimRight(string(txt.Literal), ":")
+				nd.Period = parsePeriod(nd.Title)
 			}
-			par.AddChild(tr.NewNode(nd))
+			_ = par.AddChild(tr.NewNode(nd)) // new node, cannot form a cycle
 		case blackfriday.Paragraph:
 			c, _ := cur()
 			mdParToDesc(c, n)
 		case blackfriday.List:
 			c, _ := cur()
-			c.AddChild(mdList2Tree(tr, n)...)
+			// nodes from the list are new and only reference each other
+			_ = c.AddChild(mdList2Tree(tr, n)...)
 		}
 	}
-	for len(tr.root.Sub) == 1 && tr.root.isProxyNode() {
-		tr.root = tr.root.Sub[0]
-	}
+	tr.addRoot(root)
 }
 
 func mdParToDesc(nd *Node, par *blackfriday.Node) {
@@ -147,65 +156,7 @@ func mdParToDesc(nd *Node, par *blackfriday.Node) {
 				desc = strings.TrimSpace(string(n.Literal))
 			}
 		case blackfriday.Strong:
-			key := strings.TrimSuffix(string(n.FirstChild.Literal), ":")
-			vnode := n.Next
-			if vnode == nil || vnode.Type != blackfriday.Text {
-				continue
-			}
-			n = vnode // skip text value
-			val := strings.TrimSpace(string(vnode.Literal))
-			switch key {
-			case "Progress":
-				if sub := rePerc.FindStringSubmatch(val); len(sub) > 0 {
-					perc, err := strconv.ParseFloat(sub[1], 64)
-					if err != nil {
-						log.Println(fmt.Errorf("cannot parse percents: %v", err))
-						continue
-					}
-					if v := int(perc); v != 0 {
-						nd.Progress = &Progress{Done: v, Total: 100}
-					}
-				} else if sub = reParts.FindStringSubmatch(val); len(sub) > 0 {
-					done, err := strconv.ParseInt(sub[1], 10, 64)
-					if err != nil {
-						log.Println(fmt.Errorf("cannot parse done parts: %v", err))
-						continue
-					}
-					total, err := strconv.ParseInt(sub[2], 10, 64)
-					if err != nil {
-						log.Println(fmt.Errorf("cannot parse total parts: %v", err))
-						continue
-					}
-					nd.Progress = &Progress{Done: int(done), Total: int(total)}
-				}
-			default:
-				switch {
-				case strings.HasPrefix(key, "Parent"):
-					var u Link
-					if val != "" {
-						if sub := reHashRef.FindStringSubmatch(val); len(sub) != 0 {
-							u.Title = "#" + sub[1]
-						}
-						if sub := reURL.FindStringSubmatch(val); len(sub) != 0 {
-							u.URL = sub[1]
-						}
-					} else if lnk := vnode.Next; lnk != nil && lnk.Type == blackfriday.Link {
-						n = lnk // skip link value
-						u.URL = string(lnk.LinkData.Destination)
-						if len(lnk.LinkData.Title) != 0 {
-							u.Title = string(lnk.LinkData.Title)
-						} else if txt := lnk.FirstChild; txt != nil && txt.Type == blackfriday.Text {
-							u.Title = string(txt.Literal)
-						}
-					}
-					if u.URL == "" {
-						u.URL = u.Title
-					}
-					if u != (Link{}) {
-						nd.parent = &u
-					}
-				}
-			}
+			n = mdParseField(nd, n)
 		}
 	}
 	if nd.Desc == "" {
@@ -215,6 +166,166 @@ func mdParToDesc(nd *Node, par *blackfriday.Node) {
 	}
 }
 
+// mdParseFields parses all the "**Key:** value" fields starting from a given paragraph node.
+func mdParseFields(nd *Node, n *blackfriday.Node) {
+	for ; n != nil; n = n.Next {
+		if n.Type == blackfriday.Strong {
+			n = mdParseField(nd, n)
+		}
+	}
+}
+
+// mdParseField parses a single "**Key:** value" field from a paragraph.
+// It returns the last node consumed by the field.
+func mdParseField(nd *Node, n *blackfriday.Node) *blackfriday.Node {
+	if n.FirstChild == nil {
+		return n
+	}
+	key := strings.TrimSuffix(string(n.FirstChild.Literal), ":")
+	vnode := n.Next
+	if vnode == nil || vnode.Type != blackfriday.Text {
+		return n
+	}
+	n = vnode // skip text value
+	val := strings.TrimSpace(string(vnode.Literal))
+	switch key {
+	case "Progress":
+		if sub := rePerc.FindStringSubmatch(val); len(sub) > 0 {
+			perc, err := strconv.ParseFloat(sub[1], 64)
+			if err != nil {
+				log.Println(fmt.Errorf("cannot parse percents: %v", err))
+				return n
+			}
+			if v := int(perc); v != 0 {
+				nd.Progress = &Progress{Done: v, Total: 100}
+			}
+		} else if sub = reParts.FindStringSubmatch(val); len(sub) > 0 {
+			done, err := strconv.ParseInt(sub[1], 10, 64)
+			if err != nil {
+				log.Println(fmt.Errorf("cannot parse done parts: %v", err))
+				return n
+			}
+			total, err := strconv.ParseInt(sub[2], 10, 64)
+			if err != nil {
+				log.Println(fmt.Errorf("cannot parse total parts: %v", err))
+				return n
+			}
+			nd.Progress = &Progress{Done: int(done), Total: int(total)}
+		}
+	case "Due", "Deadline":
+		t, err := parseDate(val)
+		if err != nil {
+			log.Println(fmt.Errorf("cannot parse due date: %v", err))
+			return n
+		}
+		nd.Due = t
+	case "Period", "Quarter":
+		if p := parsePeriod(val); p != "" {
+			nd.Period = p
+		} else {
+			nd.Period = val
+		}
+	case "Start":
+		// can be either a start date, or a start value of the metric
+		if t, err := parseDate(val); err == nil {
+			nd.Start = t
+			return n
+		}
+		fallthrough
+	case "Target", "Current", "Baseline":
+		v, unit, err := parseValue(val)
+		if err != nil {
+			log.Println(fmt.Errorf("cannot parse %s value: %v", strings.ToLower(key), err))
+			return n
+		}
+		if nd.Metric == nil {
+			nd.Metric = &Metric{}
+		}
+		m := nd.Metric
+		if m.Unit == "" {
+			m.Unit = unit
+		}
+		switch key {
+		case "Target":
+			m.Target = v
+		case "Current":
+			m.Current = &v
+		default:
+			m.Start = &v
+		}
+	case "Owner", "Owners", "Assignee", "Assignees":
+		nd.Owners = appendOwners(nd.Owners, parseOwners(val)...)
+	case "Team":
+		nd.Team = val
+	case "Status":
+		nd.Status = Status(strings.ToLower(val))
+	case "Kind":
+		nd.Kind = Kind(strings.ToLower(val))
+	case "Weight":
+		w, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			log.Println(fmt.Errorf("cannot parse weight: %v", err))
+			return n
+		}
+		nd.Weight = &w
+	case "Direction":
+		dir, ok := parseDirection(val)
+		if !ok {
+			log.Printf("unknown metric direction %q, expected increase or decrease", val)
+			return n
+		}
+		if nd.Metric == nil {
+			nd.Metric = &Metric{}
+		}
+		nd.Metric.Direction = dir
+	default:
+		switch {
+		case strings.HasPrefix(key, "Parent"):
+			var u Link
+			if val != "" {
+				if sub := reCrossRef.FindStringSubmatch(val); len(sub) != 0 {
+					u.Title = sub[1]
+				} else if sub := reEpicRef.FindStringSubmatch(val); len(sub) != 0 {
+					u.Title = sub[1]
+				} else if sub := reHashRef.FindStringSubmatch(val); len(sub) != 0 {
+					u.Title = "#" + sub[1]
+				}
+				if sub := reURL.FindStringSubmatch(val); len(sub) != 0 {
+					u.URL = sub[1]
+				}
+			} else if lnk := vnode.Next; lnk != nil && lnk.Type == blackfriday.Link {
+				n = lnk // skip link value
+				u.URL = string(lnk.LinkData.Destination)
+				if len(lnk.LinkData.Title) != 0 {
+					u.Title = string(lnk.LinkData.Title)
+				} else if txt := lnk.FirstChild; txt != nil && txt.Type == blackfriday.Text {
+					u.Title = string(txt.Literal)
+				}
+			}
+			if u.URL == "" {
+				u.URL = u.Title
+			}
+			if u != (Link{}) {
+				nd.parent = &u
+			}
+		}
+	}
+	return n
+}
+
+// parseValue parses a metric value with an optional unit, for example "200ms" or "1,500 users".
+func parseValue(s string) (float64, string, error) {
+	sub := reValue.FindStringSubmatch(s)
+	if len(sub) == 0 {
+		return 0, "", fmt.Errorf("unexpected value: %q", s)
+	}
+	v, err := strconv.ParseFloat(strings.Replace(sub[1], ",", "", -1), 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return v, strings.TrimSpace(sub[2]), nil
+}
+
 func mdList2Tree(tr *Tree, list *blackfriday.Node) []*Node {
 	var out []*Node
 	for n := list.FirstChild; n != nil; n = n.Next {
@@ -235,6 +346,15 @@ func parseTitle(n *Node, s string) {
 		}
 	}
 	var links []Link
+	for _, re := range []*regexp.Regexp{reCrossRef, reEpicRef} {
+		for _, sub := range re.FindAllStringSubmatch(s, -1) {
+			s = strings.Replace(s, sub[1], "", 1)
+			links = append(links, Link{
+				Title: sub[1],
+				URL:   sub[1],
+			})
+		}
+	}
 	for _, sub := range reHashRef.FindAllStringSubmatch(s, -1) {
 		s = strings.Replace(s, sub[0], "", 1)
 		links = append(links, Link{
@@ -253,9 +373,41 @@ func parseTitle(n *Node, s string) {
 	} else {
 		n.Links = links
 	}
+	// trailing mentions define owners of the item
+	var owners []string
+	for {
+		sub := reMention.FindStringSubmatch(s)
+		if len(sub) == 0 {
+			break
+		}
+		s = strings.TrimSuffix(s, sub[0])
+		owners = append([]string{sub[1]}, owners...)
+	}
+	n.Owners = appendOwners(n.Owners, owners...)
 	n.Title = strings.TrimSpace(s)
 }
 
+// parseOwners parses a list of owners like "@alice, @bob".
+func parseOwners(s string) []string {
+	var out []string
+	for _, o := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}) {
+		if o = strings.TrimPrefix(o, "@"); o != "" {
+			out = appendOwners(out, o)
+		}
+	}
+	return out
+}
+
+func formatOwners(owners []string) string {
+	out := make([]string, 0, len(owners))
+	for _, o := range owners {
+		out = append(out, "@"+o)
+	}
+	return strings.Join(out, ", ")
+}
+
 func mdItem2Tree(tr *Tree, root *blackfriday.Node) *Node {
 	var cur Node
 	for n := root.FirstChild; n != nil; n = n.Next {
@@ -271,6 +423,7 @@ func mdItem2Tree(tr *Tree, root *blackfriday.Node) *Node {
 					}
 				}
 				parseTitle(&cur, s)
+				mdParseFields(&cur, txt.Next)
 			}
 		case blackfriday.List:
 			cur.Sub = mdList2Tree(tr, n)
@@ -280,7 +433,47 @@ func mdItem2Tree(tr *Tree, root *blackfriday.Node) *Node {
 }
 
 func WriteMDTree(w io.Writer, tree *Node) error {
-	return writeMDTree(w, tree, 1, -1)
+	return writeMDTree(w, acyclic(tree), 1, -1)
+}
+
+func mdFields(node *Node) []string {
+	var out []string
+	if node.Team != "" {
+		out = append(out, "**Team:** "+node.Team)
+	}
+	if node.Period != "" && node.Period != parsePeriod(node.Title) {
+		out = append(out, "**Period:** "+node.Period)
+	}
+	if node.Status != "" {
+		out = append(out, "**Status:** "+string(node.Status))
+	}
+	if node.Kind != "" {
+		out = append(out, "**Kind:** "+string(node.Kind))
+	}
+	if node.Start != nil {
+		out = append(out, "**Start:** "+formatDate(*node.Start))
+	}
+	if node.Due != nil {
+		out = append(out, "**Due:** "+formatDate(*node.Due))
+	}
+	if w := node.Weight; w != nil {
+		out = append(out, "**Weight:** "+strconv.FormatFloat(*w, 'f', -1, 64))
+	}
+	m := node.Metric
+	if m == nil {
+		return out
+	}
+	if m.Start != nil {
+		out = append(out, "**Start:** "+formatValue(*m.Start, m.Unit))
+	}
+	out = append(out, "**Target:** "+formatValue(m.Target, m.Unit))
+	if m.Current != nil {
+		out = append(out, "**Current:** "+formatValue(*m.Current, m.Unit))
+	}
+	if m.Direction != "" {
+		out = append(out, "**Direction:** "+string(m.Direction))
+	}
+	return out
 }
 
 func writeMDTree(w io.Writer, node *Node, lvl, blvl int) error {
@@ -315,7 +508,13 @@ func writeMDTree(w io.Writer, node *Node, lvl, blvl int) error {
 			}
 			title += fmt.Sprintf(" ([%s](%s))", txt, u.URL)
 		}
+		for _, o := range node.Owners {
+			title += " @" + o
+		}
 		write("%s* %s\n", strings.Repeat("\t", blvl-1), title)
+		if fields := mdFields(node); len(fields) != 0 {
+			write("%s  %s\n", strings.Repeat("\t", blvl-1), strings.Join(fields, " "))
+		}
 		for _, c := range node.Sub {
 			if err := writeMDTree(w, c, lvl+1, blvl+1); err != nil {
 				return err
@@ -329,13 +528,27 @@ func writeMDTree(w io.Writer, node *Node, lvl, blvl int) error {
 	if node.Link.URL != "" {
 		write("[Source page](%s)\n\n", node.Link.URL)
 	}
+	if len(node.Owners) != 0 {
+		write("**Owner:** %s\n\n", formatOwners(node.Owners))
+	}
 	if p := node.GetProgress(); p != (Progress{}) {
+		// derived progress is written under a different key, so it's not read back as explicit
+		key := "Progress"
+		if node.isRolledUp() {
+			key = "Rollup"
+		}
 		if p.Total == 100 {
-			write("**Progress:** %d%%\n\n", p.Done)
+			write("**%s:** %d%%\n\n", key, p.Done)
 		} else {
-			write("**Progress:** %d/%d\n\n", p.Done, p.Total)
+			write("**%s:** %d/%d\n\n", key, p.Done, p.Total)
 		}
 	}
+	if len(node.trend) > 1 {
+		write("**Trend:** %s\n\n", Sparkline(node.trend))
+	}
+	if fields := mdFields(node); len(fields) != 0 {
+		write("%s\n\n", strings.Join(fields, "\n"))
+	}
 	if node.Desc != "" {
 		if blvl < 0 {
 			blvl = 0
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,6 +18,18 @@ func pri(v int) *int {
 	return &v
 }
 
+func val(v float64) *float64 {
+	return &v
+}
+
+func date(s string) *time.Time {
+	t, err := parseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
 var casesMDTree = []struct {
 	name string
 	md   string
@@ -147,6 +160,108 @@ Description 3
 			},
 		},
 	},
+	{
+		name: "gitlab refs",
+		md: `**Parent:** org&3
+
+- [ ] Epic &4
+- [ ] Issue org/team/app#5
+- [ ] R&D #6
+`,
+		exp: &Node{
+			parent: &Link{"org&3", "org&3"},
+			Sub: []*Node{
+				{Title: "Epic", Link: Link{"&4", "&4"}},
+				{Title: "Issue", Link: Link{"org/team/app#5", "org/team/app#5"}},
+				{Title: "R&D", Link: Link{"#6", "#6"}},
+			},
+		},
+	},
+	{
+		name: "metric",
+		md: `# Latency
+**Start:** 800ms
+**Target:** 200ms
+**Current:** 450ms
+
+- [ ] [P1] Grow users
+  **Target:** 1,500 users **Current:** 300 users
+`,
+		exp: &Node{
+			Title:  "Latency",
+			Metric: &Metric{Unit: "ms", Start: val(800), Target: 200, Current: val(450)},
+			Sub: []*Node{
+				{Title: "Grow users", Priority: pri(1), Metric: &Metric{Unit: "users", Target: 1500, Current: val(300)}},
+			},
+		},
+	},
+	{
+		name: "direction",
+		md: `# Latency
+**Target:** 200ms
+**Current:** 450ms
+**Direction:** decrease ↓
+
+- [ ] Errors
+  **Target:** 10 **Direction:** lower
+- [ ] Users
+  **Target:** 100 **Direction:** sideways
+`,
+		exp: &Node{
+			Title:  "Latency",
+			Metric: &Metric{Unit: "ms", Target: 200, Current: val(450), Direction: Decrease},
+			Sub: []*Node{
+				{Title: "Errors", Metric: &Metric{Target: 10, Direction: Decrease}},
+				{Title: "Users", Metric: &Metric{Target: 100}},
+			},
+		},
+	},
+	{
+		name: "owners",
+		md: `# Objective
+**Owner:** @alice, bob
+**Team:** platform
+
+- [ ] Ship it @carol @org/team
+- [ ] Review with @dave before release
+`,
+		exp: &Node{
+			Title:  "Objective",
+			Owners: []string{"alice", "bob"},
+			Team:   "platform",
+			Sub: []*Node{
+				{Title: "Ship it", Owners: []string{"carol", "org/team"}},
+				{Title: "Review with @dave before release"},
+			},
+		},
+	},
+	{
+		name: "periods",
+		md: `# OKRs
+
+## Q3 2026
+
+- [x] Old objective
+
+## 2026-Q4
+
+**Start:** 2026-10-01
+**Due:** 2026-12-31
+
+- [ ] New objective
+`,
+		exp: &Node{
+			Title: "OKRs",
+			Sub: []*Node{
+				{Title: "Q3 2026", Period: "2026-Q3", Sub: []*Node{
+					{Title: "Old objective", Progress: done()},
+				}},
+				{Title: "2026-Q4", Period: "2026-Q4", Start: date("2026-10-01"), Due: date("2026-12-31"), Sub: []*Node{
+					{Title: "New objective"},
+				}},
+			},
+		},
+	},
 }
 
 func TestMDTree(t *testing.T) {
@@ -155,12 +270,12 @@ func TestMDTree(t *testing.T) {
 			tr := NewTree()
 			err := ParseMDTree(strings.NewReader(c.md), tr)
 			require.NoError(t, err)
-			if !assert.ObjectsAreEqual(c.exp, tr.root) {
+			if !assert.ObjectsAreEqual(c.exp, tr.Root()) {
 				ast, err := parseMD(strings.NewReader(c.md))
 				require.NoError(t, err)
 				printMD(os.Stderr, ast, "")
 			}
-			require.Equal(t, c.exp, tr.root)
+			require.Equal(t, c.exp, tr.Root())
 		})
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"time"
 )
 
 func init() {
@@ -12,29 +14,62 @@ func init() {
 		Write: func(w io.Writer, t *Node) error {
 			enc := json.NewEncoder(w)
 			enc.SetIndent("", "\t")
-			return enc.Encode(asMindMup(t))
+			return enc.Encode(asMindMup(acyclic(t)))
 		},
 	})
+	RegisterTreeReader(TreeReaderDesc{
+		Name: "mindmup", Ext: "mup",
+		Read: func(r io.Reader, tr *Tree) error {
+			var doc mupDoc
+			if err := json.NewDecoder(r).Decode(&doc); err != nil {
+				return err
+			}
+			tr.addRoot(fromMindMup(mupNode{Title: doc.Title, Sub: doc.Sub}))
+			return nil
+		},
+	})
+}
+
+type mupNodeAttrs struct {
+	BNode  string     `json:"bnode,omitempty"`
+	URL    string     `json:"url,omitempty"`
+	Metric *Metric    `json:"metric,omitempty"`
+	Owners []string   `json:"owners,omitempty"`
+	Team   string     `json:"team,omitempty"`
+	Period string     `json:"period,omitempty"`
+	Status Status     `json:"status,omitempty"`
+	Kind   Kind       `json:"kind,omitempty"`
+	Start  *time.Time `json:"start,omitempty"`
+	Due    *time.Time `json:"due,omitempty"`
+}
+
+type mupNode struct {
+	ID    int             `json:"id"`
+	Title string          `json:"title"`
+	Attrs *mupNodeAttrs   `json:"attr,omitempty"`
+	Sub   map[int]mupNode `json:"ideas,omitempty"`
+}
+
+type mupDoc struct {
+	Vers  int             `json:"formatVersion"`
+	ID    string          `json:"id"`
+	Title string          `json:"title"`
+	Sub   map[int]mupNode `json:"ideas,omitempty"`
+	Attrs interface{}     `json:"attr,omitempty"`
+	Theme interface{}     `json:"theme,omitempty"`
 }
 
 func asMindMup(t *Node) interface{} {
-	type Attrs struct {
-		BNode string `json:"bnode,omitempty"`
-		URL   string `json:"url,omitempty"`
-	}
-	type MupNode struct {
-		ID    int             `json:"id"`
-		Title string          `json:"title"`
-		Attrs *Attrs          `json:"attr,omitempty"`
-		Sub   map[int]MupNode `json:"ideas,omitempty"`
-	}
 	var last int
-	var conv func(t *Node) MupNode
-	conv = func(t *Node) MupNode {
+	var conv func(t *Node) mupNode
+	conv = func(t *Node) mupNode {
 		last++
 		id := last
-		n := MupNode{ID: id, Title: t.Title, Sub: make(map[int]MupNode)}
-		n.Attrs = &Attrs{BNode: fmt.Sprintf("%p", t), URL: t.Link.URL}
+		n := mupNode{ID: id, Title: t.Title, Sub: make(map[int]mupNode)}
+		n.Attrs = &mupNodeAttrs{BNode: fmt.Sprintf("%p", t), URL: t.Link.URL, Metric: t.Metric,
+			Owners: t.Owners, Team: t.Team,
+			Period: t.Period, Status: t.Status, Kind: t.Kind, Start: t.Start, Due: t.Due,
+		}
 		for i, s := range t.Sub {
 			n.Sub[i+1] = conv(s)
 		}
@@ -42,14 +77,7 @@ func asMindMup(t *Node) interface{} {
 	}
 
 	root := conv(t)
-	return struct {
-		Vers  int             `json:"formatVersion"`
-		ID    string          `json:"id"`
-		Title string          `json:"title"`
-		Sub   map[int]MupNode `json:"ideas,omitempty"`
-		Attrs interface{}     `json:"attr,omitempty"`
-		Theme interface{}     `json:"theme,omitempty"`
-	}{
+	return mupDoc{
 		Vers:  3,
 		ID:    "root",
 		Title: root.Title,
@@ -59,6 +87,31 @@ func asMindMup(t *Node) interface{} {
 	}
 }
 
+func fromMindMup(m mupNode) *Node {
+	nd := &Node{Title: m.Title}
+	if m.Attrs != nil {
+		nd.Link.URL = m.Attrs.URL
+		nd.Metric = m.Attrs.Metric
+		nd.Owners = m.Attrs.Owners
+		nd.Team = m.Attrs.Team
+		nd.Period = m.Attrs.Period
+		nd.Status = m.Attrs.Status
+		nd.Kind = m.Attrs.Kind
+		nd.Start = m.Attrs.Start
+		nd.Due = m.Attrs.Due
+	}
+	// ideas are keyed by their rank; negative ranks are placed on the left side of the map
+	keys := make([]int, 0, len(m.Sub))
+	for k := range m.Sub {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		nd.Sub = append(nd.Sub, fromMindMup(m.Sub[k]))
+	}
+	return nd
+}
+
 const mupAttrs = `{
     "theme": "topdownStandard",
     "progress-statuses": {
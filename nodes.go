@@ -1,6 +1,15 @@
 package okrs
 
-import "sort"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
 
 func NewTree() *Tree {
 	return &Tree{
@@ -8,21 +17,57 @@ func NewTree() *Tree {
 	}
 }
 
+// NewLenientTree creates an empty tree for validation. Loaders record structural errors, like missing
+// parents or cycles, as problems reported by Lint.Check, instead of failing.
+func NewLenientTree() *Tree {
+	tr := NewTree()
+	tr.lenient = true
+	return tr
+}
+
 type Tree struct {
-	root *Node
+	root     *Node
+	lenient  bool
+	problems []Problem // structural errors recorded by loaders in lenient mode
 }
 
+// Root returns the root node of the tree. If sources were added to the tree as separate roots, the root
+// is a proxy node. If there is only one of them, its root is returned instead.
 func (tr *Tree) Root() *Node {
 	if tr == nil {
 		return nil
 	}
-	return tr.root
+	root := tr.root
+	for len(root.Sub) == 1 && root.isProxyNode() {
+		root = root.Sub[0]
+	}
+	return root
+}
+
+// Filter returns a new tree that contains only nodes matching the function with their sub-trees and ancestors.
+func (tr *Tree) Filter(fnc func(n *Node) bool) *Tree {
+	root := tr.Root().Filter(fnc)
+	if root == nil {
+		root = &Node{}
+	}
+	return &Tree{root: root}
 }
 
 func (tr *Tree) NewNode(nd Node) *Node {
 	return &nd
 }
 
+// addRoot attaches a node tree loaded from a separate source to the root of the tree.
+// If the root of the source is a proxy node, its children are attached instead.
+func (tr *Tree) addRoot(nd *Node) {
+	// sources build their trees from new nodes, so the root of the tree is never reachable from them
+	if nd.isProxyNode() {
+		_ = tr.root.AddChild(nd.Sub...)
+		return
+	}
+	_ = tr.root.AddChild(nd)
+}
+
 func (tr *Tree) merge(n *Node, n2 Node) {
 	if n.Title == "" {
 		n.Title = n2.Title
@@ -39,6 +84,31 @@ func (tr *Tree) merge(n *Node, n2 Node) {
 	if n.Progress == nil {
 		n.Progress = n2.Progress
 	}
+	if n.Metric == nil {
+		n.Metric = n2.Metric
+	}
+	if n.Weight == nil {
+		n.Weight = n2.Weight
+	}
+	if n.Team == "" {
+		n.Team = n2.Team
+	}
+	if n.Period == "" {
+		n.Period = n2.Period
+	}
+	if n.Status == "" {
+		n.Status = n2.Status
+	}
+	if n.Kind == "" {
+		n.Kind = n2.Kind
+	}
+	if n.Start == nil {
+		n.Start = n2.Start
+	}
+	if n.Due == nil {
+		n.Due = n2.Due
+	}
+	n.Owners = appendOwners(n.Owners, n2.Owners...)
 	if len(n.Sub) == 0 {
 		n.Sub = n2.Sub
 	}
@@ -50,21 +120,100 @@ type Link struct {
 }
 
 type Node struct {
-	ID       string    `json:"id,omitempty" yaml:"id,omitempty"`
-	Title    string    `json:"title,omitempty" yaml:"title,omitempty"`
-	Desc     string    `json:"desc,omitempty" yaml:"desc,omitempty"`
-	Link     Link      `json:"url,omitempty" yaml:"url,omitempty"`
-	Priority *int      `json:"priority,omitempty" yaml:"priority,omitempty"`
-	Progress *Progress `json:"progress,omitempty" yaml:"progress,omitempty"`
-	Sub      []*Node   `json:"sub,omitempty" yaml:"sub,omitempty"`
-	Links    []Link    `json:"links,omitempty" yaml:"links,omitempty"`
+	ID       string     `json:"id,omitempty" yaml:"id,omitempty"`
+	Title    string     `json:"title,omitempty" yaml:"title,omitempty"`
+	Desc     string     `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Link     Link       `json:"url,omitempty" yaml:"url,omitempty"`
+	Priority *int       `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Progress *Progress  `json:"progress,omitempty" yaml:"progress,omitempty"`
+	Metric   *Metric    `json:"metric,omitempty" yaml:"metric,omitempty"`
+	Weight   *float64   `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Owners   []string   `json:"owners,omitempty" yaml:"owners,omitempty"`
+	Team     string     `json:"team,omitempty" yaml:"team,omitempty"`
+	Period   string     `json:"period,omitempty" yaml:"period,omitempty"`
+	Status   Status     `json:"status,omitempty" yaml:"status,omitempty"`
+	Kind     Kind       `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Start    *time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	Due      *time.Time `json:"due,omitempty" yaml:"due,omitempty"`
+	Sub      []*Node    `json:"sub,omitempty" yaml:"sub,omitempty"`
+	Links    []Link     `json:"links,omitempty" yaml:"links,omitempty"`
 
 	parent *Link
+	trend  []float64 // progress history, see AnnotateTrends
+	rollup *Progress // progress derived from sub-nodes by Rollup.Apply; it is never persisted
+}
+
+// isRolledUp checks if the progress of the node is derived by Rollup.Apply, instead of being set explicitly.
+func (n *Node) isRolledUp() bool {
+	return n.rollup != nil && (n.Progress == nil || *n.Progress == (Progress{}))
 }
 
 func (n *Node) isProxyNode() bool {
 	return n.parent == nil && n.ID == "" && n.Title == "" && n.Desc == "" &&
-		n.Link == (Link{}) && n.Priority == nil && n.Progress == nil && len(n.Links) == 0
+		n.Link == (Link{}) && n.Priority == nil && n.Progress == nil && n.Metric == nil && n.Weight == nil &&
+		len(n.Owners) == 0 && n.Team == "" && n.Period == "" && n.Status == "" && n.Kind == "" && n.Start == nil && n.Due == nil &&
+		len(n.Links) == 0
+}
+
+// HasOwner checks if a given user or team owns the node. The name is compared case-insensitively.
+func (n *Node) HasOwner(name string) bool {
+	name = strings.TrimPrefix(name, "@")
+	for _, o := range n.Owners {
+		if strings.EqualFold(o, name) {
+			return true
+		}
+	}
+	return strings.EqualFold(n.Team, name)
+}
+
+// appendOwners adds owners to the list, skipping duplicates.
+func appendOwners(owners []string, arr ...string) []string {
+loop:
+	for _, o := range arr {
+		for _, o2 := range owners {
+			if strings.EqualFold(o, o2) {
+				continue loop
+			}
+		}
+		owners = append(owners, o)
+	}
+	return owners
+}
+
+// Filter returns a copy of the tree that contains only nodes matching the function with their
+// sub-trees and ancestors. It returns nil if no nodes matched.
+func (n *Node) Filter(fnc func(n *Node) bool) *Node {
+	return acyclic(n).filter(fnc)
+}
+
+func (n *Node) filter(fnc func(n *Node) bool) *Node {
+	if n == nil {
+		return nil
+	} else if fnc(n) {
+		return n
+	}
+	var sub []*Node
+	for _, s := range n.Sub {
+		if s2 := s.filter(fnc); s2 != nil {
+			sub = append(sub, s2)
+		}
+	}
+	if len(sub) == 0 {
+		return nil
+	}
+	return n.withSub(sub)
+}
+
+// withSub returns a copy of the node with a subset of its sub-nodes.
+// The copy keeps the progress of the original node, so filtered views report the same progress.
+func (n *Node) withSub(sub []*Node) *Node {
+	n2 := *n
+	n2.Sub = sub
+	if len(sub) != len(n.Sub) && n.Progress == nil && n.rollup == nil {
+		p := n.GetProgress()
+		n2.Progress = &p
+	}
+	return &n2
 }
 
 func (n *Node) Sort() {
@@ -82,16 +231,36 @@ func (n *Node) Sort() {
 }
 
 func (n *Node) GetProgress() Progress {
+	return n.getProgress(make(map[*Node]struct{}))
+}
+
+// getProgress calculates the progress of the node. Sub-nodes that are already on the path
+// from the root are considered not done to prevent infinite recursion on cyclic graphs.
+func (n *Node) getProgress(path map[*Node]struct{}) Progress {
 	if n.Progress != nil && *n.Progress != (Progress{}) {
 		return *n.Progress
 	}
-	total := len(n.Sub)
+	if n.Metric != nil {
+		if p, ok := n.Metric.Progress(); ok {
+			return p
+		}
+	}
+	if n.rollup != nil {
+		return *n.rollup
+	}
+	path[n] = struct{}{}
+	defer delete(path, n)
+	total := 0
 	done := 0
 	for _, sub := range n.Sub {
-		if sub == n {
-			panic("self-reference")
+		if sub.Status == StatusCanceled {
+			continue
+		}
+		total++
+		if _, ok := path[sub]; ok {
+			continue
 		}
-		p := sub.GetProgress()
+		p := sub.getProgress(path)
 		if p.IsDone() {
 			done++
 		}
@@ -99,26 +268,129 @@ func (n *Node) GetProgress() Progress {
 	return Progress{Done: done, Total: total}
 }
 
-func (n *Node) AddChild(arr ...*Node) {
+// CycleError is returned when adding a node would create a cycle in the tree.
+type CycleError struct {
+	// Path is a list of nodes that form a cycle. The first and the last nodes are the same.
+	Path []*Node
+}
+
+func (e *CycleError) Error() string {
+	if len(e.Path) <= 2 {
+		return fmt.Sprintf("%s references itself", nodeName(e.Path[0]))
+	}
+	names := make([]string, 0, len(e.Path))
+	for _, n := range e.Path {
+		names = append(names, nodeName(n))
+	}
+	return "cycle detected: " + strings.Join(names, " -> ")
+}
+
+// pathTo returns a path from n to the dst node, or nil if dst is not reachable.
+func (n *Node) pathTo(dst *Node) []*Node {
+	seen := make(map[*Node]struct{})
+	var find func(n *Node) []*Node
+	find = func(n *Node) []*Node {
+		if n == dst {
+			return []*Node{n}
+		}
+		if _, ok := seen[n]; ok {
+			return nil
+		}
+		seen[n] = struct{}{}
+		for _, s := range n.Sub {
+			if p := find(s); p != nil {
+				return append([]*Node{n}, p...)
+			}
+		}
+		return nil
+	}
+	return find(n)
+}
+
+// AddChild adds nodes as children of n, skipping the ones that were already added.
+// It returns CycleError if one of the nodes is n itself or one of its ancestors. In this case, no nodes are added.
+func (n *Node) AddChild(arr ...*Node) error {
 	if len(arr) == 0 {
-		return
+		return nil
 	}
 	m := make(map[*Node]struct{}, len(n.Sub))
 	for _, n2 := range n.Sub {
 		m[n2] = struct{}{}
 	}
+	// check all nodes first, so the node is not modified on errors
+	add := make([]*Node, 0, len(arr))
 	for _, n2 := range arr {
 		if n2 == nil {
 			panic("nil node")
-		} else if n2 == n {
-			panic("self-reference")
 		}
 		if _, ok := m[n2]; ok {
 			continue
 		}
-		n.Sub = append(n.Sub, n2)
+		if p := n2.pathTo(n); p != nil {
+			return &CycleError{Path: append([]*Node{n}, p...)}
+		}
+		add = append(add, n2)
 		m[n2] = struct{}{}
 	}
+	n.Sub = append(n.Sub, add...)
+	return nil
+}
+
+// hasCycles checks if there are any cycles in the graph reachable from the node.
+func (n *Node) hasCycles() bool {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*Node]int)
+	var check func(n *Node) bool
+	check = func(n *Node) bool {
+		switch state[n] {
+		case visiting:
+			return true
+		case visited:
+			return false
+		}
+		state[n] = visiting
+		for _, s := range n.Sub {
+			if check(s) {
+				return true
+			}
+		}
+		state[n] = visited
+		return false
+	}
+	return check(n)
+}
+
+// acyclic returns a tree without cycles. If there are cycles in the graph, it returns a copy
+// of it with all edges that lead back to an ancestor removed. Otherwise, the node is returned as-is.
+// Nodes with multiple parents are preserved.
+func acyclic(root *Node) *Node {
+	if root == nil || !root.hasCycles() {
+		return root
+	}
+	copies := make(map[*Node]*Node)
+	path := make(map[*Node]struct{})
+	var conv func(n *Node) *Node
+	conv = func(n *Node) *Node {
+		if c, ok := copies[n]; ok {
+			return c
+		}
+		c := *n
+		c.Sub = nil
+		copies[n] = &c
+		path[n] = struct{}{}
+		for _, s := range n.Sub {
+			if _, ok := path[s]; ok {
+				continue
+			}
+			c.Sub = append(c.Sub, conv(s))
+		}
+		delete(path, n)
+		return &c
+	}
+	return conv(root)
 }
 
 type Progress struct {
@@ -129,3 +401,143 @@ type Progress struct {
 func (p Progress) IsDone() bool {
 	return p.Done == p.Total
 }
+
+// Fraction returns the progress as a number in [0, 1] range.
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Done) / float64(p.Total)
+}
+
+// Status is a state of the node reported by the source, for example by the issue tracker.
+type Status string
+
+const (
+	StatusDone = Status("done")
+	// StatusCanceled marks nodes that won't be done. They are excluded from the progress of the parent.
+	StatusCanceled = Status("canceled")
+)
+
+// Kind is an explicit type of the node in the OKR tree. Nodes without a kind are classified by their depth.
+type Kind string
+
+const (
+	KindObjective = Kind("objective")
+	KindKeyResult = Kind("key_result")
+)
+
+// Direction specifies if the metric value should go up or down to reach the target.
+type Direction string
+
+const (
+	Increase = Direction("increase")
+	Decrease = Direction("decrease")
+)
+
+// parseDirection parses common spellings of the metric direction, like "down", "lower" or "decrease ↓".
+func parseDirection(s string) (Direction, bool) {
+	s = strings.ToLower(s)
+	switch {
+	case strings.ContainsAny(s, "↑⬆"):
+		return Increase, true
+	case strings.ContainsAny(s, "↓⬇"):
+		return Decrease, true
+	}
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, w := range words {
+		switch w {
+		case "increase", "increasing", "up", "higher", "more", "grow", "raise", "maximize", "max":
+			return Increase, true
+		case "decrease", "decreasing", "down", "lower", "less", "reduce", "minimize", "min":
+			return Decrease, true
+		}
+	}
+	return "", false
+}
+
+// Metric is a quantitative measure of a key result, like "reduce p99 latency from 800ms to 200ms".
+type Metric struct {
+	Unit      string    `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Start     *float64  `json:"start,omitempty" yaml:"start,omitempty"`
+	Target    float64   `json:"target" yaml:"target"`
+	Current   *float64  `json:"current,omitempty" yaml:"current,omitempty"`
+	Direction Direction `json:"direction,omitempty" yaml:"direction,omitempty"`
+}
+
+// GetDirection returns the direction of the metric. If it's not set explicitly, it is derived from start and target values.
+func (m Metric) GetDirection() Direction {
+	if m.Direction != "" {
+		return m.Direction
+	}
+	if m.Start != nil && *m.Start > m.Target {
+		return Decrease
+	}
+	return Increase
+}
+
+// Fraction returns a normalized completion of the metric in [0, 1] range.
+// It returns false if there is not enough data to calculate it.
+func (m Metric) Fraction() (float64, bool) {
+	if m.Current == nil {
+		return 0, false
+	}
+	cur := *m.Current
+	dec := m.GetDirection() == Decrease
+	var start float64
+	if m.Start != nil {
+		start = *m.Start
+	} else if dec {
+		// no baseline for the metric that should go down - can only tell if it's reached
+		if cur <= m.Target {
+			return 1, true
+		}
+		return 0, true
+	}
+	if start == m.Target {
+		if (dec && cur <= m.Target) || (!dec && cur >= m.Target) {
+			return 1, true
+		}
+		return 0, true
+	}
+	f := (cur - start) / (m.Target - start)
+	if f < 0 {
+		f = 0
+	} else if f > 1 {
+		f = 1
+	}
+	return f, true
+}
+
+// Progress converts metric completion to a percentage progress.
+func (m Metric) Progress() (Progress, bool) {
+	f, ok := m.Fraction()
+	if !ok {
+		return Progress{}, false
+	}
+	return Progress{Done: int(math.Round(f * 100)), Total: 100}, true
+}
+
+func formatValue(v float64, unit string) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if unit == "" {
+		return s
+	}
+	if r, _ := utf8.DecodeRuneInString(unit); unicode.IsLetter(r) && len(unit) > 2 {
+		// separate full words from the value: "10 users", but "200ms"
+		return s + " " + unit
+	}
+	return s + unit
+}
+
+// String returns a short human-readable representation of the metric.
+func (m Metric) String() string {
+	s := formatValue(m.Target, m.Unit)
+	if m.Start != nil {
+		s = formatValue(*m.Start, m.Unit) + " -> " + s
+	}
+	if m.Current != nil {
+		s = formatValue(*m.Current, m.Unit) + " of " + s
+	}
+	return s
+}
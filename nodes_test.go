@@ -0,0 +1,187 @@
+package okrs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var casesMetricProgress = []struct {
+	name string
+	m    Metric
+	exp  *Progress
+}{
+	{
+		name: "no current",
+		m:    Metric{Target: 200},
+	},
+	{
+		name: "increase",
+		m:    Metric{Target: 200, Current: val(50)},
+		exp:  &Progress{Done: 25, Total: 100},
+	},
+	{
+		name: "decrease",
+		m:    Metric{Start: val(800), Target: 200, Current: val(450)},
+		exp:  &Progress{Done: 58, Total: 100},
+	},
+	{
+		name: "decrease no start",
+		m:    Metric{Target: 200, Current: val(150), Direction: Decrease},
+		exp:  &Progress{Done: 100, Total: 100},
+	},
+	{
+		name: "overshoot",
+		m:    Metric{Start: val(10), Target: 20, Current: val(30)},
+		exp:  &Progress{Done: 100, Total: 100},
+	},
+	{
+		name: "regression",
+		m:    Metric{Start: val(800), Target: 200, Current: val(900)},
+		exp:  &Progress{Done: 0, Total: 100},
+	},
+}
+
+func TestMetricProgress(t *testing.T) {
+	for _, c := range casesMetricProgress {
+		t.Run(c.name, func(t *testing.T) {
+			p, ok := c.m.Progress()
+			if c.exp == nil {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, *c.exp, p)
+		})
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	for in, exp := range map[string]Direction{
+		"Increase": Increase, "up": Increase, "Higher is better": Increase, "↑": Increase,
+		"decrease": Decrease, "Down": Decrease, "lower": Decrease, "decrease ↓": Decrease, "reduce": Decrease,
+	} {
+		dir, ok := parseDirection(in)
+		require.True(t, ok, in)
+		require.Equal(t, exp, dir, in)
+	}
+	_, ok := parseDirection("sideways")
+	require.False(t, ok)
+}
+
+func TestFilterPeriod(t *testing.T) {
+	tr := &Tree{root: &Node{
+		Title: "OKRs",
+		Sub: []*Node{
+			{Title: "Q3", Period: "2026-Q3", Sub: []*Node{
+				{Title: "Old", Progress: done()},
+				{Title: "Moved", Period: "2026-Q4", Progress: &Progress{Done: 0, Total: 1}},
+			}},
+			{Title: "Q4", Period: "2026-Q4", Sub: []*Node{
+				{Title: "New", Progress: done()},
+			}},
+			{Title: "Due", Due: date("2026-11-15"), Progress: done()},
+			{Title: "Unknown", Progress: &Progress{Done: 0, Total: 1}},
+		},
+	}}
+	out := tr.FilterPeriod("Q4 2026")
+	require.Equal(t, &Node{
+		Title: "OKRs",
+		Sub: []*Node{
+			{Title: "Q3", Period: "2026-Q3", Sub: []*Node{
+				{Title: "Moved", Period: "2026-Q4", Progress: &Progress{Done: 0, Total: 1}},
+			}},
+			{Title: "Q4", Period: "2026-Q4", Sub: []*Node{
+				{Title: "New", Progress: done()},
+			}},
+			{Title: "Due", Due: date("2026-11-15"), Progress: done()},
+		},
+	}, out.Root())
+
+	// progress only includes nodes of the period
+	root := out.Root()
+	require.Equal(t, Progress{Done: 2, Total: 3}, root.GetProgress())
+	require.Equal(t, Progress{Done: 0, Total: 1}, root.Sub[0].GetProgress())
+	require.Equal(t, Progress{Done: 2, Total: 4}, tr.Root().GetProgress())
+
+	// rolled up progress of removed sub-nodes is not kept
+	require.NoError(t, Rollup{Mode: RollupWeighted}.Apply(tr.Root()))
+	require.Equal(t, Progress{Done: 63, Total: 100}, tr.Root().GetProgress())
+	root = tr.FilterPeriod("2026-Q4").Root()
+	require.Equal(t, Progress{Done: 2, Total: 3}, root.GetProgress())
+}
+
+func TestAddChildCycle(t *testing.T) {
+	a := &Node{Title: "A", Link: Link{Title: "#1"}}
+	b := &Node{Title: "B", Link: Link{Title: "#2"}}
+	c := &Node{Title: "C", Link: Link{Title: "#3"}}
+
+	err := a.AddChild(a)
+	require.EqualError(t, err, "#1 A references itself")
+
+	require.NoError(t, a.AddChild(b))
+	require.NoError(t, b.AddChild(c))
+	err = c.AddChild(a)
+	require.EqualError(t, err, "cycle detected: #3 C -> #1 A -> #2 B -> #3 C")
+	require.Empty(t, c.Sub)
+
+	// the node is not modified if any of the nodes would form a cycle
+	d := &Node{Title: "D"}
+	err = c.AddChild(d, a)
+	require.Error(t, err)
+	require.Empty(t, c.Sub)
+
+	// multiple parents are allowed
+	require.NoError(t, a.AddChild(c))
+	require.Equal(t, []*Node{b, c}, a.Sub)
+}
+
+func TestCyclicWriters(t *testing.T) {
+	a := &Node{Title: "A"}
+	b := &Node{Title: "B", Progress: done()}
+	c := &Node{Title: "C"}
+	// build the cycle directly, bypassing AddChild checks
+	a.Sub = []*Node{b, c}
+	b.Sub = []*Node{c}
+	c.Sub = []*Node{a, c}
+	require.Equal(t, Progress{Done: 1, Total: 2}, a.GetProgress())
+	for _, w := range TreeWriters() {
+		t.Run(w.Name, func(t *testing.T) {
+			err := w.Write(io.Discard, a)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestMultipleSources(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a", "b"} {
+		path := filepath.Join(dir, name+".md")
+		doc := "# Objective " + name + "\n\n* KR " + name + "\n"
+		require.NoError(t, ioutil.WriteFile(path, []byte(doc), 0644))
+		paths = append(paths, path)
+	}
+	c := &Config{Markdown: paths[:1]}
+	tr, err := c.LoadTree(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Objective a", tr.Root().Title)
+
+	c.Markdown = paths
+	tr, err = c.LoadTree(context.Background())
+	require.NoError(t, err)
+	// other sources, like Jira projects, must not be attached to the first source
+	tr.addRoot(&Node{Title: "Project", Sub: []*Node{{Title: "Issue"}}})
+
+	root := tr.Root()
+	require.True(t, root.isProxyNode())
+	require.Len(t, root.Sub, 3)
+	for i, title := range []string{"Objective a", "Objective b", "Project"} {
+		require.Equal(t, title, root.Sub[i].Title)
+		require.Len(t, root.Sub[i].Sub, 1)
+	}
+}
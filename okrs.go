@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -37,9 +38,21 @@ func ReadConfig(path string) (*Config, error) {
 type Output struct {
 	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
 	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	// Owner limits the output to objectives owned by a given user or team.
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// Period limits the output to objectives from a given OKR period, for example "2026-Q4".
+	Period string `json:"period,omitempty" yaml:"period,omitempty"`
 }
 
 func (o Output) WriteTree(tr *Tree) error {
+	if o.Owner != "" {
+		tr = tr.Filter(func(n *Node) bool {
+			return n.HasOwner(o.Owner)
+		})
+	}
+	if o.Period != "" {
+		tr = tr.FilterPeriod(o.Period)
+	}
 	var wr *TreeWriterDesc
 	if ext := filepath.Ext(o.Path); ext != "" && o.Format == "" {
 		for _, w := range TreeWriters() {
@@ -67,25 +80,73 @@ func (o Output) WriteTree(tr *Tree) error {
 		defer f.Close()
 		w = f
 	}
-	return wr.Write(w, tr.root)
+	return wr.Write(w, tr.Root())
+}
+
+type Input struct {
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+}
+
+func (in Input) ReadTree(tr *Tree) error {
+	var rd *TreeReaderDesc
+	if ext := filepath.Ext(in.Path); ext != "" && in.Format == "" {
+		for _, r := range TreeReaders() {
+			if ext == "."+r.Ext {
+				rd = &r
+				break
+			}
+		}
+	} else {
+		rd = TreeReader(in.Format)
+	}
+	if rd == nil {
+		return fmt.Errorf("unknown format %q", in.Format)
+	}
+	var r io.Reader = os.Stdin
+	if name := in.Path; name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return rd.Read(r, tr)
 }
 
 type Config struct {
 	Github   *Github  `json:"github,omitempty" yaml:"github,omitempty"`
+	Gitlab   *Gitlab  `json:"gitlab,omitempty" yaml:"gitlab,omitempty"`
+	Jira     *Jira    `json:"jira,omitempty" yaml:"jira,omitempty"`
+	Gitea    *Gitea   `json:"gitea,omitempty" yaml:"gitea,omitempty"`
 	Markdown []string `json:"markdown,omitempty" yaml:"markdown,omitempty"`
+	Inputs   []Input  `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Rollup   *Rollup  `json:"rollup,omitempty" yaml:"rollup,omitempty"`
+	Lint     *Lint    `json:"lint,omitempty" yaml:"lint,omitempty"`
+	History  *History `json:"history,omitempty" yaml:"history,omitempty"`
 	Output   []Output `json:"output,omitempty" yaml:"output,omitempty"`
 }
 
-func (c *Config) Run(ctx context.Context) error {
+// LoadTree reads OKR tree from all inputs specified in the config.
+func (c *Config) LoadTree(ctx context.Context) (*Tree, error) {
 	tr := NewTree()
+	if err := c.ReadTree(ctx, tr); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// ReadTree reads all inputs specified in the config into the tree.
+func (c *Config) ReadTree(ctx context.Context, tr *Tree) error {
 	for _, path := range c.Markdown {
-		f, err := os.Open(path)
-		if err != nil {
+		in := Input{Path: path, Format: "md"}
+		if err := in.ReadTree(tr); err != nil {
 			return err
 		}
-		err = ParseMDTree(f, tr)
-		f.Close()
-		if err != nil {
+	}
+	for _, in := range c.Inputs {
+		if err := in.ReadTree(tr); err != nil {
 			return err
 		}
 	}
@@ -94,6 +155,41 @@ func (c *Config) Run(ctx context.Context) error {
 			return err
 		}
 	}
+	if c.Gitlab != nil {
+		if err := c.Gitlab.LoadTree(ctx, tr); err != nil {
+			return err
+		}
+	}
+	if c.Jira != nil {
+		if err := c.Jira.LoadTree(ctx, tr); err != nil {
+			return err
+		}
+	}
+	if c.Gitea != nil {
+		if err := c.Gitea.LoadTree(ctx, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) Run(ctx context.Context) error {
+	tr, err := c.LoadTree(ctx)
+	if err != nil {
+		return err
+	}
+	if c.Rollup != nil {
+		if err := c.Rollup.Apply(tr.Root()); err != nil {
+			return err
+		}
+	}
+	if c.History != nil {
+		snaps, err := c.History.Load()
+		if err != nil {
+			return err
+		}
+		AnnotateTrends(tr, snaps)
+	}
 	if len(c.Output) == 0 {
 		return fmt.Errorf("no outputs specified")
 	}
@@ -102,5 +198,11 @@ func (c *Config) Run(ctx context.Context) error {
 			return err
 		}
 	}
+	if c.History != nil {
+		// record the snapshot only after a successful run, so failed runs don't affect trends
+		if err := c.History.Record(NewSnapshot(tr, time.Now())); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package okrs
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+var (
+	rePeriod    = regexp.MustCompile(`\b(\d{4})[-\s]?Q([1-4])\b`)
+	rePeriodRev = regexp.MustCompile(`\bQ([1-4])[-\s]?(\d{4})\b`)
+)
+
+// parsePeriod finds an OKR period like "2026-Q4" or "Q4 2026" in the string and returns it in a normalized form.
+func parsePeriod(s string) string {
+	if sub := rePeriod.FindStringSubmatch(s); len(sub) != 0 {
+		return sub[1] + "-Q" + sub[2]
+	} else if sub = rePeriodRev.FindStringSubmatch(s); len(sub) != 0 {
+		return sub[2] + "-Q" + sub[1]
+	}
+	return ""
+}
+
+// periodOf returns an OKR period (quarter) for a given time.
+func periodOf(t time.Time) string {
+	return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+func parseDate(s string) (*time.Time, error) {
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(dateFormat)
+}
+
+// GetPeriod returns an OKR period of the node, either set explicitly, or derived from the due date.
+func (n *Node) GetPeriod() string {
+	if n.Period != "" {
+		return n.Period
+	} else if n.Due != nil {
+		return periodOf(*n.Due)
+	}
+	return ""
+}
+
+// FilterPeriod returns a new tree that contains only nodes from a given OKR period and their ancestors.
+// Nodes without a period inherit it from the parent. Unlike Filter, progress of nodes with removed sub-nodes
+// is computed from the remaining sub-nodes, so the view only reports progress of the period.
+func (tr *Tree) FilterPeriod(period string) *Tree {
+	if p := parsePeriod(period); p != "" {
+		period = p
+	}
+	root := filterPeriod(acyclic(tr.Root()), period, "")
+	if root == nil {
+		root = &Node{}
+	}
+	return &Tree{root: root}
+}
+
+func filterPeriod(n *Node, period, inherited string) *Node {
+	cur := n.GetPeriod()
+	if cur == "" {
+		cur = inherited
+	}
+	var sub []*Node
+	for _, s := range n.Sub {
+		if s2 := filterPeriod(s, period, cur); s2 != nil {
+			sub = append(sub, s2)
+		}
+	}
+	if cur != period && len(sub) == 0 {
+		return nil
+	}
+	n2 := *n
+	n2.Sub = sub
+	if len(sub) != len(n.Sub) {
+		n2.rollup = nil // derived from removed sub-nodes
+	}
+	return &n2
+}
@@ -0,0 +1,124 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// envToken reads an API token from the environment variable.
+func envToken(name string) (string, error) {
+	tok := strings.TrimSpace(os.Getenv(name))
+	if tok == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return tok, nil
+}
+
+// configToken returns the token from the config. If it's not set, the token is read from the environment variable, if any.
+func configToken(tok, env string) (string, error) {
+	if tok != "" || env == "" {
+		return tok, nil
+	}
+	return envToken(env)
+}
+
+// apiError is returned for unsuccessful responses of REST APIs.
+type apiError struct {
+	API    string // name of the API, for example "jira"
+	Path   string
+	Code   int
+	Status string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.API, e.Path, e.Status)
+}
+
+// isNotFound checks if the error is an API response for a missing or removed resource.
+func isNotFound(err error) bool {
+	e, ok := err.(*apiError)
+	return ok && (e.Code == http.StatusNotFound || e.Code == http.StatusGone)
+}
+
+// isForbidden checks if the error is an API response for a resource that is not accessible with the credentials.
+func isForbidden(err error) bool {
+	e, ok := err.(*apiError)
+	return ok && e.Code == http.StatusForbidden
+}
+
+// restClient sends authorized requests to a JSON REST API.
+type restClient struct {
+	name string // name of the API used in errors
+	base string // base URL of the API
+	hcli *http.Client
+	// auth sets credentials of the request.
+	auth func(req *http.Request)
+	// page returns query parameters that select a page of a list, starting from 1.
+	page func(page int) string
+	// hasNext checks if there are more pages after the response.
+	hasNext func(resp *http.Response) bool
+}
+
+// do requests an API path and decodes the response.
+func (c *restClient) do(ctx context.Context, path string, out interface{}) (*http.Response, error) {
+	req, err := http.NewRequest("GET", c.base+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if c.auth != nil {
+		c.auth(req)
+	}
+	hcli := c.hcli
+	if hcli == nil {
+		hcli = http.DefaultClient
+	}
+	resp, err := hcli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, &apiError{API: c.name, Path: path, Code: resp.StatusCode, Status: resp.Status}
+	}
+	return resp, json.NewDecoder(resp.Body).Decode(out)
+}
+
+// get requests an API path and decodes the response.
+func (c *restClient) get(ctx context.Context, path string, out interface{}) error {
+	_, err := c.do(ctx, path, out)
+	return err
+}
+
+// list requests all pages of an API list and decodes them into out, which must be a pointer to a slice.
+func (c *restClient) list(ctx context.Context, path string, out interface{}) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	var all []json.RawMessage
+	for page := 1; ; page++ {
+		var buf []json.RawMessage
+		resp, err := c.do(ctx, path+sep+c.page(page), &buf)
+		if err != nil {
+			return err
+		}
+		all = append(all, buf...)
+		if len(buf) == 0 || !c.hasNext(resp) {
+			break
+		}
+	}
+	if all == nil {
+		all = []json.RawMessage{}
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,117 @@
+package okrs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeREST serves static JSON pages for REST API paths. Paths are matched before unescaping.
+// Requests without a page parameter get the first page, which is used for single objects.
+type fakeREST struct {
+	header, token string                   // header that must be set to the token
+	query         map[string]string        // query parameters required for lists
+	pages         map[string][]interface{} // pages of responses by path
+	// next announces the next page of a list.
+	next func(w http.ResponseWriter, r *http.Request, page int)
+}
+
+func newFakeREST(t testing.TB, f *fakeREST) *httptest.Server {
+	if f.pages == nil {
+		f.pages = make(map[string][]interface{})
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeREST) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(f.header) != f.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	pages, ok := f.pages[r.URL.EscapedPath()]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	if q.Get("page") == "" {
+		json.NewEncoder(w).Encode(pages[0])
+		return
+	}
+	for k, v := range f.query {
+		if q.Get(k) != v {
+			http.Error(w, "unexpected "+k, http.StatusBadRequest)
+			return
+		}
+	}
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 || page > len(pages) {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	if page < len(pages) {
+		f.next(w, r, page+1)
+	}
+	json.NewEncoder(w).Encode(pages[page-1])
+}
+
+func TestRESTClient(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeREST{
+		header: "Authorization", token: "token secret",
+		next: func(w http.ResponseWriter, r *http.Request, page int) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page))
+		},
+	}
+	srv := newFakeREST(t, fake)
+	fake.pages["/api/items"] = []interface{}{[]int{1, 2}, []int{3}}
+	fake.pages["/api/items/1"] = []interface{}{map[string]int{"id": 1}}
+
+	cli := &restClient{
+		name: "test", base: srv.URL + "/api",
+		auth: func(req *http.Request) {
+			req.Header.Set("Authorization", "token secret")
+		},
+		page: func(page int) string {
+			return "page=" + strconv.Itoa(page)
+		},
+		hasNext: func(resp *http.Response) bool {
+			return resp.Header.Get("X-Next-Page") != ""
+		},
+	}
+	var list []int
+	require.NoError(t, cli.list(ctx, "items", &list))
+	require.Equal(t, []int{1, 2, 3}, list)
+
+	var item struct {
+		ID int `json:"id"`
+	}
+	require.NoError(t, cli.get(ctx, "items/1", &item))
+	require.Equal(t, 1, item.ID)
+
+	err := cli.get(ctx, "items/2", &item)
+	require.True(t, isNotFound(err))
+	require.EqualError(t, err, "test: items/2: 404 Not Found")
+
+	cli.auth = nil
+	err = cli.list(ctx, "items", &list)
+	require.Error(t, err)
+	require.False(t, isNotFound(err))
+
+	t.Setenv("OKRS_TEST_TOKEN", " secret\n")
+	tok, err := configToken("", "OKRS_TEST_TOKEN")
+	require.NoError(t, err)
+	require.Equal(t, "secret", tok)
+	tok, err = configToken("direct", "OKRS_TEST_TOKEN")
+	require.NoError(t, err)
+	require.Equal(t, "direct", tok)
+	_, err = configToken("", "OKRS_NO_TOKEN")
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package okrs
+
+import (
+	"fmt"
+	"math"
+)
+
+type RollupMode string
+
+const (
+	// RollupCount counts the number of completed sub-nodes. This is the default.
+	RollupCount = RollupMode("count")
+	// RollupWeighted calculates the progress as a weighted average of fractional progress of sub-nodes.
+	RollupWeighted = RollupMode("weighted")
+)
+
+type WeightsMode string
+
+const (
+	// WeightsEqual assigns the same weight to all nodes without an explicit weight.
+	WeightsEqual = WeightsMode("equal")
+	// WeightsPriority derives the weight of the node from its priority.
+	WeightsPriority = WeightsMode("priority")
+)
+
+// defaultPriorityWeights is used for WeightsPriority if no custom weights are specified.
+var defaultPriorityWeights = map[int]float64{
+	0: 4, 1: 3, 2: 2, 3: 1,
+}
+
+// Rollup configures how the progress of sub-nodes is combined into the progress of the parent.
+type Rollup struct {
+	Mode    RollupMode  `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Weights WeightsMode `json:"weights,omitempty" yaml:"weights,omitempty"`
+	// PriorityWeights overrides weights for each priority level.
+	PriorityWeights map[int]float64 `json:"priority_weights,omitempty" yaml:"priority_weights,omitempty"`
+}
+
+func (r Rollup) validate() error {
+	switch r.Mode {
+	case "", RollupCount, RollupWeighted:
+	default:
+		return fmt.Errorf("unknown rollup mode: %q", r.Mode)
+	}
+	switch r.Weights {
+	case "", WeightsEqual, WeightsPriority:
+	default:
+		return fmt.Errorf("unknown weights mode: %q", r.Weights)
+	}
+	for p, w := range r.PriorityWeights {
+		if w <= 0 {
+			return fmt.Errorf("weight of P%d must be positive, got %v", p, w)
+		}
+	}
+	return nil
+}
+
+// checkWeights returns an error if any node in the tree has a weight that is not positive.
+func checkWeights(n *Node, seen map[*Node]struct{}) error {
+	if _, ok := seen[n]; ok {
+		return nil
+	}
+	seen[n] = struct{}{}
+	if n.Weight != nil && *n.Weight <= 0 {
+		return fmt.Errorf("weight of %q must be positive, got %v", n.Title, *n.Weight)
+	}
+	for _, s := range n.Sub {
+		if err := checkWeights(s, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// weight returns the weight of the node when calculating the progress of its parent.
+func (r Rollup) weight(n *Node) float64 {
+	if n.Weight != nil {
+		return *n.Weight
+	}
+	if r.Weights != WeightsPriority || n.Priority == nil {
+		return 1
+	}
+	weights := r.PriorityWeights
+	if len(weights) == 0 {
+		weights = defaultPriorityWeights
+	}
+	if w, ok := weights[*n.Priority]; ok {
+		return w
+	}
+	// lower than any known priority
+	return 1
+}
+
+// Apply calculates the progress of all nodes in the tree that don't have an explicit progress.
+// It has no effect for the default (count) mode, since GetProgress already implements it.
+func (r Rollup) Apply(root *Node) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	if r.Mode != RollupWeighted {
+		return nil
+	}
+	if err := checkWeights(root, make(map[*Node]struct{})); err != nil {
+		return err
+	}
+	r.fraction(root, make(map[*Node]float64))
+	return nil
+}
+
+func (n *Node) metricFraction() (float64, bool) {
+	if n.Metric == nil {
+		return 0, false
+	}
+	return n.Metric.Fraction()
+}
+
+// fraction returns the progress of the node in [0, 1] range. For nodes without an explicit progress,
+// it also stores the derived progress, so GetProgress can report it.
+func (r Rollup) fraction(n *Node, seen map[*Node]float64) float64 {
+	if f, ok := seen[n]; ok {
+		return f
+	}
+	seen[n] = 0
+	var f float64
+	if p := n.Progress; p != nil && p.Total > 0 {
+		f = float64(p.Done) / float64(p.Total)
+	} else if mf, ok := n.metricFraction(); ok {
+		f = mf
+	} else if len(n.Sub) != 0 {
+		var sum, total float64
+		for _, s := range n.Sub {
+			if s.Status == StatusCanceled {
+				continue
+			}
+			w := r.weight(s)
+			sum += w * r.fraction(s, seen)
+			total += w
+		}
+		if total > 0 {
+			f = sum / total
+		}
+		n.rollup = &Progress{Done: int(math.Round(f * 100)), Total: 100}
+	}
+	seen[n] = f
+	return f
+}
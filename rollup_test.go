@@ -0,0 +1,103 @@
+package okrs
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollupWeighted(t *testing.T) {
+	kr := func(perc int, prio *int) *Node {
+		return &Node{Priority: prio, Progress: &Progress{Done: perc, Total: 100}}
+	}
+	cases := []struct {
+		name string
+		r    Rollup
+		root *Node
+		exp  Progress
+	}{
+		{
+			name: "count",
+			r:    Rollup{},
+			root: &Node{Sub: []*Node{kr(90, nil), kr(90, nil), kr(90, nil)}},
+			exp:  Progress{Done: 0, Total: 3},
+		},
+		{
+			name: "equal",
+			r:    Rollup{Mode: RollupWeighted},
+			root: &Node{Sub: []*Node{kr(90, nil), kr(90, nil), kr(90, nil)}},
+			exp:  Progress{Done: 90, Total: 100},
+		},
+		{
+			name: "explicit weight",
+			r:    Rollup{Mode: RollupWeighted},
+			root: &Node{Sub: []*Node{
+				{Weight: val(3), Progress: done()},
+				{Weight: val(1)},
+			}},
+			exp: Progress{Done: 75, Total: 100},
+		},
+		{
+			name: "priority",
+			r:    Rollup{Mode: RollupWeighted, Weights: WeightsPriority},
+			root: &Node{Sub: []*Node{kr(100, pri(0)), kr(0, pri(3))}},
+			exp:  Progress{Done: 80, Total: 100},
+		},
+		{
+			name: "nested",
+			r:    Rollup{Mode: RollupWeighted},
+			root: &Node{Sub: []*Node{
+				{Sub: []*Node{kr(50, nil), {Progress: done()}}},
+				{Metric: &Metric{Target: 10, Current: val(5)}},
+			}},
+			exp: Progress{Done: 63, Total: 100},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.r.Apply(c.root)
+			require.NoError(t, err)
+			require.Equal(t, c.exp, c.root.GetProgress())
+		})
+	}
+}
+
+func TestRollupNotPersisted(t *testing.T) {
+	root := &Node{Title: "Objective", Sub: []*Node{
+		{Title: "KR 1", Progress: &Progress{Done: 30, Total: 100}},
+		{Title: "KR 2", Progress: &Progress{Done: 60, Total: 100}},
+	}}
+	require.NoError(t, Rollup{Mode: RollupWeighted}.Apply(root))
+	require.Equal(t, Progress{Done: 45, Total: 100}, root.GetProgress())
+	require.Nil(t, root.Progress)
+
+	data, err := json.Marshal(root)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "45")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMDTree(&buf, root))
+	require.Contains(t, buf.String(), "**Rollup:** 45%")
+	tr := NewTree()
+	require.NoError(t, ParseMDTree(&buf, tr))
+	require.Nil(t, tr.Root().Progress)
+
+	// derived progress follows changes of sub-nodes
+	root.Sub[1].Progress = &Progress{Done: 100, Total: 100}
+	require.NoError(t, Rollup{Mode: RollupWeighted}.Apply(root))
+	require.Equal(t, Progress{Done: 65, Total: 100}, root.GetProgress())
+}
+
+func TestRollupInvalidWeights(t *testing.T) {
+	root := &Node{Sub: []*Node{{Title: "KR", Weight: val(0)}, {Weight: val(1)}}}
+	err := Rollup{Mode: RollupWeighted}.Apply(root)
+	require.EqualError(t, err, `weight of "KR" must be positive, got 0`)
+
+	root.Sub[0].Weight = val(-1)
+	require.Error(t, Rollup{Mode: RollupWeighted}.Apply(root))
+
+	r := Rollup{Mode: RollupWeighted, Weights: WeightsPriority, PriorityWeights: map[int]float64{0: 2, 1: 0}}
+	require.EqualError(t, r.Apply(&Node{}), "weight of P1 must be positive, got 0")
+}
@@ -0,0 +1,172 @@
+package okrs
+
+import (
+	"context"
+	"fmt"
+)
+
+// issueTracker finds issues referenced from bodies of other issues of the same tracker.
+type issueTracker interface {
+	// lookup finds the issue referenced by a link from the body of another issue. Trackers may fetch
+	// the issue on demand, and add it to the resolver. It returns nil if the issue is not found,
+	// and sets skip if the issue exists, but it's excluded by filters.
+	lookup(ctx context.Context, from *trackerIssue, l Link) (is *trackerIssue, skip bool, err error)
+}
+
+// trackerIssue is an issue loaded from an issue tracker.
+type trackerIssue struct {
+	node  *Node
+	local *Node // tree parsed from the issue body, if any
+	// scope is a tracker-specific scope of local "#N" references, for example a repository.
+	scope string
+	// parent is the parent issue from the native hierarchy of the tracker, if any.
+	parent *trackerIssue
+	// top is a node that holds the issue if it has no parent, for example a repository.
+	top *Node
+}
+
+// issueResolver builds a tree from issues of a tracker. Issues are linked to their native parents,
+// then to parents referenced in issue bodies, and sub-items listed in issue bodies are attached to them.
+type issueResolver struct {
+	tr      *Tree
+	tracker issueTracker
+	issues  []*trackerIssue
+}
+
+// add registers the issue in the resolver.
+func (r *issueResolver) add(is *trackerIssue) {
+	r.issues = append(r.issues, is)
+}
+
+// resolve links all issues and attaches top-level issues to their containers.
+func (r *issueResolver) resolve(ctx context.Context) error {
+	// issues fetched on demand are added while iterating
+	for i := 0; i < len(r.issues); i++ {
+		is := r.issues[i]
+		if err := r.resolveParent(ctx, is); err != nil {
+			return err
+		}
+		if is.local == nil {
+			continue
+		}
+		err := attachLocal(r.tr, is.node, is.local, func(s *Node) (*Node, error) {
+			sub, skip, err := r.tracker.lookup(ctx, is, s.Link)
+			if err != nil || skip {
+				return nil, err
+			} else if sub == nil {
+				return localNode(r.tr, s), nil
+			}
+			return sub.node, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, is := range r.issues {
+		if is.node.parent == nil {
+			if err := is.top.AddChild(is.node); err != nil {
+				return err
+			}
+		}
+	}
+	for _, is := range r.issues {
+		markOpen(is.node)
+		is.node.Sort()
+	}
+	return nil
+}
+
+// resolveParent links the issue to its native parent, or to the parent referenced in its body.
+func (r *issueResolver) resolveParent(ctx context.Context, is *trackerIssue) error {
+	par := is.parent
+	if par == nil && is.local != nil && is.local.parent != nil {
+		l := *is.local.parent
+		p, skip, err := r.tracker.lookup(ctx, is, l)
+		if err != nil {
+			return err
+		} else if p == nil && !skip {
+			return r.tr.loadError(RuleUnresolvedParent, is.node,
+				fmt.Errorf("cannot find parent of %s: %+v", is.node.Link.URL, l))
+		}
+		// issues with excluded parents stay at the top level
+		par = p
+	}
+	if p := is.node.parent; p != nil {
+		// already listed in the body of another issue
+		if par != nil && par.node.Link.URL != p.URL {
+			return r.tr.loadError(RuleConflictingParent, is.node,
+				fmt.Errorf("incorrect parent of %s: %v (from parent link) vs %v (from local subtree)",
+					is.node.Link.URL, par.node.Link.Title, p.Title))
+		}
+		return nil
+	} else if par == nil {
+		return nil
+	}
+	if err := par.node.AddChild(is.node); err != nil {
+		return r.tr.loadError(RuleCycle, is.node, fmt.Errorf("invalid parent link in %s: %v", is.node.Link.URL, err))
+	}
+	is.node.parent = &par.node.Link
+	return nil
+}
+
+// attachLocal attaches sub-items of the tree parsed from the issue body to the issue node.
+// Items are resolved with find, which returns a known issue, a new node created by localNode,
+// or nil if the item is excluded. Items that cannot be attached are skipped in lenient mode.
+func attachLocal(tr *Tree, root, local *Node, find func(s *Node) (*Node, error)) error {
+	for _, s := range local.Sub {
+		sn, err := find(s)
+		if err != nil {
+			return err
+		} else if sn == nil {
+			continue
+		}
+		mergeLocal(sn, s)
+		if p := sn.parent; p == nil {
+			if err := root.AddChild(sn); err != nil {
+				err = fmt.Errorf("invalid sub-issue in %s: %v", nodeName(root), err)
+				if err = tr.loadError(RuleCycle, sn, err); err != nil {
+					return err
+				}
+				continue
+			}
+			l := root.Link
+			sn.parent = &l
+		} else if p.URL != root.Link.URL {
+			err = fmt.Errorf("incorrect parent of %v: %v (from parent link) vs %v (from local subtree)",
+				nodeName(sn), p.Title, root.Link.Title)
+			if err = tr.loadError(RuleConflictingParent, sn, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := attachLocal(tr, sn, s, find); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localNode creates a node for an item of the tree parsed from the issue body that doesn't reference
+// a known issue. Other fields of the item are filled by mergeLocal.
+func localNode(tr *Tree, s *Node) *Node {
+	return tr.NewNode(Node{ID: s.ID, Title: s.Title, Link: s.Link})
+}
+
+// addContainers adds nodes that hold top-level issues, like repositories or projects, to the tree.
+// Empty containers are skipped.
+func addContainers(tr *Tree, conts []*Node) error {
+	root := tr.NewNode(Node{})
+	for _, c := range conts {
+		c.Sort()
+		if len(c.Sub) != 0 {
+			if err := root.AddChild(c); err != nil {
+				return err
+			}
+		}
+	}
+	if len(root.Sub) == 1 {
+		root = root.Sub[0]
+	}
+	tr.addRoot(root)
+	return nil
+}
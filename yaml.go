@@ -11,7 +11,18 @@ func init() {
 		Name: "yaml", Ext: "yml",
 		Write: func(w io.Writer, t *Node) error {
 			enc := yaml.NewEncoder(w)
-			return enc.Encode(t)
+			return enc.Encode(acyclic(t))
+		},
+	})
+	RegisterTreeReader(TreeReaderDesc{
+		Name: "yaml", Ext: "yml",
+		Read: func(r io.Reader, tr *Tree) error {
+			var nd Node
+			if err := yaml.NewDecoder(r).Decode(&nd); err != nil {
+				return err
+			}
+			tr.addRoot(&nd)
+			return nil
 		},
 	})
 }